@@ -0,0 +1,108 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// Fingerprinted assets are resolved through a JSON manifest produced by the
+// asset build, mapping logical names to the fingerprinted ones:
+//
+//	{"app.js": "app.abc123.js"}
+//
+// It is configured in app.conf, a relative manifest path is resolved against
+// the application base path:
+//
+//	assets.manifest = public/manifest.json
+//	assets.prefix   = /public/
+//
+// The manifest is read at startup and whenever the config is reloaded.
+//
+// The asset files are served from a root which may differ in dev mode, e.g.
+// the sources in dev and the build output otherwise:
+//
+//	assets.root.dev  = app/assets
+//	assets.root.prod = public/dist
+//
+// Either falls back to assets.root, which defaults to public.
+var (
+	assetManifest     map[string]string
+	assetManifestLock sync.RWMutex
+)
+
+func init() {
+	OnAppStart(loadAssetManifest)
+	OnConfigReload(loadAssetManifest)
+}
+
+// loadAssetManifest reads the configured manifest. The previous manifest is
+// dropped if it can not be read.
+func loadAssetManifest() {
+	manifest := map[string]string{}
+	if path := Config.StringDefault("assets.manifest", ""); path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(BasePath, path)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &manifest)
+		}
+		if err != nil {
+			ERROR.Printf("Failed to load asset manifest %s: %s", path, err)
+		}
+	}
+
+	assetManifestLock.Lock()
+	assetManifest = manifest
+	assetManifestLock.Unlock()
+}
+
+// AssetURL returns the URL of the fingerprinted asset for a logical name,
+// or of the name itself when the manifest has no entry for it.
+// It is available to the templates as {{asset "app.js"}}.
+func AssetURL(name string) string {
+	assetManifestLock.RLock()
+	fingerprinted, found := assetManifest[name]
+	assetManifestLock.RUnlock()
+	if !found {
+		fingerprinted = name
+	}
+	return Config.StringDefault("assets.prefix", "") + fingerprinted
+}
+
+// AssetPath returns the path of the file of a logical asset name in the
+// asset root of the run mode, see assets.root.dev and assets.root.prod.
+func AssetPath(name string) string {
+	key := "assets.root.prod"
+	if DevMode {
+		key = "assets.root.dev"
+	}
+	root := Config.StringDefault(key, Config.StringDefault("assets.root", "public"))
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(BasePath, root)
+	}
+	// Keep the name within the root
+	return filepath.Join(root, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+// RenderAsset returns a response serving the file of a logical asset name
+// from the asset root of the run mode, or a 404 if there is none.
+func (c *Controller) RenderAsset(name string) Result {
+	file, err := os.Open(AssetPath(name))
+	if err != nil {
+		return c.NotFound("No such asset: %s", name)
+	}
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		_ = file.Close()
+		return c.NotFound("No such asset: %s", name)
+	}
+	return c.RenderFile(file, Inline)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"html/template"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssetURL(t *testing.T) {
+	startFakeBookingApp()
+	eq(t, "without manifest", "js/app.js", AssetURL("js/app.js"))
+
+	Config.SetOption("assets.manifest", "public/manifest.json")
+	Config.SetOption("assets.prefix", "/public/")
+	loadAssetManifest()
+	defer func() {
+		Config.SetOption("assets.manifest", "")
+		loadAssetManifest()
+	}()
+	eq(t, "mapped", "/public/js/app.abc123.js", AssetURL("js/app.js"))
+	eq(t, "unmapped", "/public/js/other.js", AssetURL("js/other.js"))
+
+	tmpl := template.Must(template.New("assets").Funcs(TemplateFuncs).Parse(`<script src="{{asset "js/app.js"}}"></script>`))
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "template", `<script src="/public/js/app.abc123.js"></script>`, b.String())
+}
+
+func TestAssetPath(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("assets.root.dev", "app")
+	Config.SetOption("assets.root.prod", "public")
+	defer func() {
+		Config.SetOption("assets.root.dev", "")
+		Config.SetOption("assets.root.prod", "")
+		DevMode = false
+	}()
+
+	DevMode = true
+	dev := AssetPath("js/app.js")
+	DevMode = false
+	prod := AssetPath("js/app.js")
+	eq(t, "dev path", dev, filepath.Join(BasePath, "app", "js", "app.js"))
+	eq(t, "prod path", prod, filepath.Join(BasePath, "public", "js", "app.js"))
+	eq(t, "escaping path", AssetPath("../conf/app.conf"), filepath.Join(BasePath, "public", "conf", "app.conf"))
+
+	c := NewController(NewRequest(httptest.NewRequest("GET", "/", nil)), NewResponse(httptest.NewRecorder()))
+	if _, ok := c.RenderAsset("index.html").(*BinaryResult); !ok {
+		t.Error("expected the prod asset to be served")
+	}
+	DevMode = true
+	if _, ok := c.RenderAsset("index.html").(ErrorResult); !ok {
+		t.Error("expected no dev asset")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io"
+	"sync"
+)
+
+var (
+	// The writers of the loggers in log.async mode, see FlushLogs
+	asyncLogWriters     []*asyncLogWriter
+	asyncLogWritersLock sync.Mutex
+)
+
+// asyncLogWriter writes the log records on a goroutine of its own, so the
+// logging goroutine doesn't wait for the output. Records are buffered up to
+// log.async.buffer, beyond which logging blocks rather than losing any.
+type asyncLogWriter struct {
+	w       io.Writer
+	records chan asyncLogRecord
+	done    chan struct{} // Closed once the records have all been written
+
+	// Once closed the records are written synchronously, for the loggers
+	// still holding the writer
+	closed     bool
+	closedLock sync.RWMutex
+}
+
+// asyncLogRecord is a record to write, or a flush with the channel to close
+// once the records before it have been written.
+type asyncLogRecord struct {
+	data    []byte
+	flushed chan struct{}
+}
+
+// newAsyncLogWriter starts writing to w asynchronously.
+func newAsyncLogWriter(w io.Writer) *asyncLogWriter {
+	writer := &asyncLogWriter{
+		w:       w,
+		records: make(chan asyncLogRecord, Config.IntDefault("log.async.buffer", 1024)),
+		done:    make(chan struct{}),
+	}
+	go writer.run()
+
+	asyncLogWritersLock.Lock()
+	asyncLogWriters = append(asyncLogWriters, writer)
+	asyncLogWritersLock.Unlock()
+	return writer
+}
+
+func (w *asyncLogWriter) run() {
+	defer close(w.done)
+	for record := range w.records {
+		if record.flushed != nil {
+			close(record.flushed)
+			continue
+		}
+		_, _ = w.w.Write(record.data)
+	}
+}
+
+// Write queues a copy of the record, the logger reuses p.
+func (w *asyncLogWriter) Write(p []byte) (int, error) {
+	w.closedLock.RLock()
+	defer w.closedLock.RUnlock()
+	if w.closed {
+		return w.w.Write(p)
+	}
+	w.records <- asyncLogRecord{data: append([]byte(nil), p...)}
+	return len(p), nil
+}
+
+// flush waits for the records queued so far to be written.
+func (w *asyncLogWriter) flush() {
+	w.closedLock.RLock()
+	if w.closed {
+		w.closedLock.RUnlock()
+		return
+	}
+	flushed := make(chan struct{})
+	w.records <- asyncLogRecord{flushed: flushed}
+	w.closedLock.RUnlock()
+	<-flushed
+}
+
+// close writes the queued records and stops the goroutine writing them.
+func (w *asyncLogWriter) close() {
+	w.closedLock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.records)
+	}
+	w.closedLock.Unlock()
+	<-w.done
+}
+
+// FlushLogs waits for the queued log records to be written in log.async
+// mode. It is run when the server stops, after the OnAppStop hooks, and
+// before exiting.
+func FlushLogs() {
+	asyncLogWritersLock.Lock()
+	writers := asyncLogWriters
+	asyncLogWritersLock.Unlock()
+	for _, writer := range writers {
+		writer.flush()
+	}
+}
+
+// closeAsyncLogs writes the queued records and stops the asynchronous
+// writers, when the loggers are replaced.
+func closeAsyncLogs() {
+	asyncLogWritersLock.Lock()
+	writers := asyncLogWriters
+	asyncLogWriters = nil
+	asyncLogWritersLock.Unlock()
+	for _, writer := range writers {
+		writer.close()
+	}
+}
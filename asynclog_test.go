@@ -0,0 +1,109 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowLogOutput is a log output taking a while for every record.
+type slowLogOutput struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (o *slowLogOutput) Write(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+	o.Lock()
+	defer o.Unlock()
+	return o.Buffer.Write(p)
+}
+
+func (o *slowLogOutput) lines() int {
+	o.Lock()
+	defer o.Unlock()
+	return strings.Count(o.String(), "\n")
+}
+
+// blockedLogOutput is a log output which doesn't write until it is released.
+type blockedLogOutput struct {
+	slowLogOutput
+	released chan struct{}
+}
+
+func (o *blockedLogOutput) Write(p []byte) (int, error) {
+	<-o.released
+	return o.slowLogOutput.Write(p)
+}
+
+func TestAsyncLog(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("log.async", "true")
+	Config.SetOption("log.async.buffer", "100")
+	if _, ok := getLogger("request").Writer().(*asyncLogWriter); !ok {
+		t.Fatal("Expected log.async to make the logger asynchronous")
+	}
+	if _, ok := getLogger("error").Writer().(*asyncLogWriter); ok {
+		t.Error("Expected the error logger to stay synchronous")
+	}
+
+	// Logging returns while the output is still blocked
+	output := &blockedLogOutput{released: make(chan struct{})}
+	writer := newAsyncLogWriter(output)
+	logger := log.New(writer, "", 0)
+	for i := 0; i < 50; i++ {
+		logger.Printf("record %d", i)
+	}
+	eq(t, "records written while blocked", output.lines(), 0)
+
+	close(output.released)
+	writer.flush()
+	eq(t, "records written once flushed", output.lines(), 50)
+	if !strings.HasPrefix(output.String(), "record 0\nrecord 1\n") {
+		t.Errorf("Expected the records in order, got %q", output.String())
+	}
+}
+
+// Test that a logger still holding a closed writer writes synchronously.
+func TestAsyncLogWriteAfterClose(t *testing.T) {
+	startFakeBookingApp()
+	output := &slowLogOutput{}
+	writer := newAsyncLogWriter(output)
+	logger := log.New(writer, "", 0)
+	logger.Println("queued")
+	writer.close()
+	eq(t, "queued record written on close", output.lines(), 1)
+
+	logger.Println("after close")
+	writer.flush()
+	eq(t, "record written after close", output.String(), "queued\nafter close\n")
+}
+
+func TestAsyncLogFlushedOnStop(t *testing.T) {
+	startFakeBookingApp()
+	_, stop, err := RunTest()
+	if err != nil {
+		t.Fatalf("Failed to run: %s", err)
+	}
+
+	output := &slowLogOutput{}
+	logger := log.New(newAsyncLogWriter(output), "", 0)
+	for i := 0; i < 50; i++ {
+		logger.Println("record", i)
+	}
+	defer func(hooks []func()) { shutdownHooks = hooks }(shutdownHooks)
+	OnAppStop(func() { logger.Println("stop hook") })
+	stop()
+	eq(t, "records written on stop", output.lines(), 51)
+	if !strings.HasSuffix(output.String(), fmt.Sprintln("record", 49)+"stop hook\n") {
+		t.Errorf("Expected the stop hook's record written last, got %q", output.String())
+	}
+}
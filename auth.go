@@ -0,0 +1,94 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// BasicAuthVerifier verifies the credentials of requests passing the
+// BasicAuthFilter. Requests are rejected while it is nil.
+var BasicAuthVerifier func(user, password string) bool
+
+// BasicAuthCredentials returns a BasicAuthVerifier accepting a single user,
+// comparing the credentials in constant time.
+func BasicAuthCredentials(user, password string) func(string, string) bool {
+	expected := []byte(user + ":" + password)
+	return func(user, password string) bool {
+		return subtle.ConstantTimeCompare([]byte(user+":"+password), expected) == 1
+	}
+}
+
+// BasicAuthFilter requires HTTP basic authentication, verified by the
+// BasicAuthVerifier, responding with a 401 to requests without valid
+// credentials. It is configured in app.conf:
+//
+//	auth.basic.realm = Admin
+//	auth.basic.paths = /admin/, /internal/
+//
+// The paths are prefixes of the request paths requiring authentication; when
+// none are configured, every request passing the filter does. They are
+// matched like the router matches the routes: a prefix like /admin/ covers
+// /admin too, and letter case is ignored with router.caseinsensitive.
+func BasicAuthFilter(c *Controller, fc []Filter) {
+	if !authRequired(c.Request.URL.Path, "auth.basic.paths") {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	user, password, ok := c.Request.BasicAuth()
+	if ok && BasicAuthVerifier != nil && BasicAuthVerifier(user, password) {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	realm := Config.StringDefault("auth.basic.realm", "Restricted")
+	c.Response.Out.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+	c.Result = c.Unauthorized("Authentication required")
+}
+
+// authRequired reports whether the path starts with one of the prefixes
+// configured in key, which is the case for any path when none are.
+func authRequired(path, key string) bool {
+	prefixes := configPrefixes(key)
+	return len(prefixes) == 0 || hasPathPrefix(path, prefixes)
+}
+
+// configPrefixes returns the comma separated path prefixes configured in key.
+func configPrefixes(key string) []string {
+	var prefixes []string
+	for _, prefix := range strings.Split(Config.StringDefault(key, ""), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// hasPathPrefix reports whether the path starts with one of the prefixes,
+// given the path and its trailing slash as the router sees them, so that a
+// request can't reach a route while escaping a prefix of it.
+func hasPathPrefix(requestPath string, prefixes []string) bool {
+	requestPath = path.Clean("/" + requestPath)
+	if requestPath != "/" {
+		requestPath += "/"
+	}
+	caseInsensitive := MainRouter != nil && MainRouter.CaseInsensitive
+	if caseInsensitive {
+		requestPath = strings.ToLower(requestPath)
+	}
+	for _, prefix := range prefixes {
+		if caseInsensitive {
+			prefix = strings.ToLower(prefix)
+		}
+		if strings.HasPrefix(requestPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
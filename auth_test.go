@@ -0,0 +1,66 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuthFilter(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("auth.basic.realm", "Admin")
+	Config.SetOption("auth.basic.paths", "/admin/")
+	defer func() { BasicAuthVerifier = nil }()
+	BasicAuthVerifier = BasicAuthCredentials("admin", "secret")
+
+	filter := func(path string, setAuth func(r *http.Request)) *Controller {
+		r := httptest.NewRequest("GET", path, nil)
+		if setAuth != nil {
+			setAuth(r)
+		}
+		c := NewController(NewRequest(r), NewResponse(httptest.NewRecorder()))
+		BasicAuthFilter(c, NilChain)
+		return c
+	}
+
+	c := filter("/admin/users", func(r *http.Request) { r.SetBasicAuth("admin", "secret") })
+	eq(t, "valid credentials result", c.Result, nil)
+
+	c = filter("/admin/users", func(r *http.Request) { r.SetBasicAuth("admin", "wrong") })
+	eq(t, "invalid credentials status", c.Response.Status, http.StatusUnauthorized)
+	eq(t, "invalid credentials challenge", c.Response.Out.Header().Get("WWW-Authenticate"), `Basic realm="Admin"`)
+
+	c = filter("/admin/users", nil)
+	eq(t, "missing header status", c.Response.Status, http.StatusUnauthorized)
+	eq(t, "missing header challenge", c.Response.Out.Header().Get("WWW-Authenticate"), `Basic realm="Admin"`)
+
+	c = filter("/hotels", nil)
+	eq(t, "unprotected path result", c.Result, nil)
+}
+
+// Test that the paths can't be escaped with the trailing slash or the letter
+// case the router ignores.
+func TestBasicAuthPathsAsRouted(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("auth.basic.paths", "/admin/")
+	defer func(caseInsensitive bool) { MainRouter.CaseInsensitive = caseInsensitive }(MainRouter.CaseInsensitive)
+
+	filter := func(method, path string) *Controller {
+		c := NewController(NewRequest(httptest.NewRequest(method, path, nil)), NewResponse(httptest.NewRecorder()))
+		BasicAuthFilter(c, NilChain)
+		return c
+	}
+
+	eq(t, "without the trailing slash", filter("GET", "/admin").Response.Status, http.StatusUnauthorized)
+	eq(t, "with a double slash", filter("GET", "//admin/users").Response.Status, http.StatusUnauthorized)
+	eq(t, "a longer name", filter("GET", "/administrator").Result, nil)
+
+	MainRouter.CaseInsensitive = false
+	eq(t, "case sensitive", filter("POST", "/ADMIN/delete").Result, nil)
+	MainRouter.CaseInsensitive = true
+	eq(t, "case insensitive", filter("POST", "/ADMIN/delete").Response.Status, http.StatusUnauthorized)
+}
@@ -5,7 +5,6 @@
 package revel
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -45,16 +44,54 @@ type Binder struct {
 }
 
 // ValueBinder is adapter for easily making one-key-value binders.
+// When the param is repeated, e.g. ?id=1&id=2, the first value is bound, or
+// the last one with binding.duplicates=last. Slices bind all of them.
 func ValueBinder(f func(value string, typ reflect.Type) reflect.Value) func(*Params, string, reflect.Type) reflect.Value {
 	return func(params *Params, name string, typ reflect.Type) reflect.Value {
 		vals, ok := params.Values[name]
 		if !ok || len(vals) == 0 {
 			return reflect.Zero(typ)
 		}
+		if len(vals) > 1 && Config.StringDefault("binding.duplicates", "first") == "last" {
+			return f(vals[len(vals)-1], typ)
+		}
 		return f(vals[0], typ)
 	}
 }
 
+// BindError describes a param which could not be converted to the type it
+// is bound to. The failures of a request are added to its validation
+// context, see ValidationError.Bind.
+type BindError struct {
+	Field string       // The param name, e.g. "id" or "user.Age"
+	Value string       // The raw input
+	Type  reflect.Type // The type bound to
+	Err   error        // The conversion error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("Invalid value %q for %s: expected %s (%s)", e.Value, e.Field, e.Type, e.Err)
+}
+
+// convertingBinder is like ValueBinder for a conversion which can fail, and
+// records the failure in the params as a BindError.
+func convertingBinder(f func(value string, typ reflect.Type) (reflect.Value, error)) func(*Params, string, reflect.Type) reflect.Value {
+	return func(params *Params, name string, typ reflect.Type) reflect.Value {
+		return ValueBinder(func(value string, typ reflect.Type) reflect.Value {
+			if len(value) == 0 {
+				return reflect.Zero(typ)
+			}
+			v, err := f(value, typ)
+			if err != nil {
+				WARN.Println(err)
+				params.bindErrors = append(params.bindErrors, &BindError{name, value, typ, err})
+				return reflect.Zero(typ)
+			}
+			return v
+		})(params, name, typ)
+	}
+}
+
 // Revel's default date and time constants
 const (
 	DefaultDateFormat     = "2006-01-02"
@@ -76,18 +113,14 @@ var (
 	DateTimeFormat string
 
 	IntBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
+		Bind: convertingBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
 			intValue, err := strconv.ParseInt(val, 10, 64)
 			if err != nil {
-				WARN.Println(err)
-				return reflect.Zero(typ)
+				return reflect.Value{}, err
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetInt(intValue)
-			return pValue.Elem()
+			return pValue.Elem(), nil
 		}),
 		Unbind: func(output map[string]string, key string, val interface{}) {
 			output[key] = fmt.Sprintf("%d", val)
@@ -95,18 +128,14 @@ var (
 	}
 
 	UintBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
+		Bind: convertingBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
 			uintValue, err := strconv.ParseUint(val, 10, 64)
 			if err != nil {
-				WARN.Println(err)
-				return reflect.Zero(typ)
+				return reflect.Value{}, err
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetUint(uintValue)
-			return pValue.Elem()
+			return pValue.Elem(), nil
 		}),
 		Unbind: func(output map[string]string, key string, val interface{}) {
 			output[key] = fmt.Sprintf("%d", val)
@@ -114,18 +143,14 @@ var (
 	}
 
 	FloatBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
+		Bind: convertingBinder(func(val string, typ reflect.Type) (reflect.Value, error) {
 			floatValue, err := strconv.ParseFloat(val, 64)
 			if err != nil {
-				WARN.Println(err)
-				return reflect.Zero(typ)
+				return reflect.Value{}, err
 			}
 			pValue := reflect.New(typ)
 			pValue.Elem().SetFloat(floatValue)
-			return pValue.Elem()
+			return pValue.Elem(), nil
 		}),
 		Unbind: func(output map[string]string, key string, val interface{}) {
 			output[key] = fmt.Sprintf("%f", val)
@@ -203,7 +228,8 @@ type sliceValue struct {
 // This function creates a slice of the given type, Binds each of the individual
 // elements, and then sets them to their appropriate location in the slice.
 // If elements are provided without an explicit index, they are added (in
-// unspecified order) to the end of the slice.
+// unspecified order) to the end of the slice. The values of the param without
+// brackets, e.g. ?id=1&id=2, are added in the order they were sent.
 func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 	// Collect an array of slice elements with their indexes (and the max index).
 	maxIndex := -1
@@ -212,6 +238,22 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 
 	// Factor out the common slice logic (between form values and files).
 	processElement := func(key string, vals []string, files []*multipart.FileHeader) {
+		// The values of the param without brackets, e.g. ?id=1&id=2, are
+		// elements too. The ones failing to convert are left out, with their
+		// BindErrors kept in the params.
+		if key == name {
+			for _, val := range vals {
+				valParams := &Params{Values: map[string][]string{name: {val}}}
+				value := Bind(valParams, name, typ.Elem())
+				if len(valParams.bindErrors) > 0 {
+					params.bindErrors = append(params.bindErrors, valParams.bindErrors...)
+					continue
+				}
+				numNoIndex++
+				sliceValues = append(sliceValues, sliceValue{index: -1, value: value})
+			}
+			return
+		}
 		if !strings.HasPrefix(key, name+"[") {
 			return
 		}
@@ -293,11 +335,10 @@ func unbindSlice(output map[string]string, name string, val interface{}) {
 func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 	resultPointer := reflect.New(typ)
 	result := resultPointer.Elem()
+	defer bindHeaders(params, result)
 	if params.JSON != nil {
 		// Try to inject the response as a json into the created result
-		if err := json.Unmarshal(params.JSON, resultPointer.Interface()); err != nil {
-			WARN.Println("W: bindStruct: Unable to unmarshal request:", name, err)
-		}
+		params.bindJSON(name, resultPointer.Interface())
 		return result
 	}
 	fieldValues := make(map[string]reflect.Value)
@@ -332,6 +373,28 @@ func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 	return result
 }
 
+// bindHeaders sets the fields of the struct tagged with the name of a request
+// header, e.g. `header:"X-Tenant-Id"`, to the value of the header converted
+// by the binder of the field type. Headers win over the other params.
+func bindHeaders(params *Params, result reflect.Value) {
+	if len(params.header) == 0 {
+		return
+	}
+	typ := result.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Tag.Get("header")
+		if name == "" {
+			continue
+		}
+		value := params.header.Get(name)
+		fieldValue := result.Field(i)
+		if value == "" || !fieldValue.CanSet() {
+			continue
+		}
+		fieldValue.Set(BindValue(value, fieldValue.Type()))
+	}
+}
+
 func unbindStruct(output map[string]string, name string, iface interface{}) {
 	val := reflect.ValueOf(iface)
 	typ := val.Type()
@@ -424,9 +487,7 @@ func bindMap(params *Params, name string, typ reflect.Type) reflect.Value {
 	result.Set(reflect.MakeMap(typ))
 	if params.JSON != nil {
 		// Try to inject the response as a json into the created result
-		if err := json.Unmarshal(params.JSON, resultPtr.Interface()); err != nil {
-			WARN.Println("W: bindMap: Unable to unmarshal request:", name, err)
-		}
+		params.bindJSON(name, resultPtr.Interface())
 		return result
 	}
 
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"sort"
@@ -167,6 +168,7 @@ var fileBindings = []struct{ val, arrval, f interface{} }{
 }
 
 func TestJsonBinder(t *testing.T) {
+	startFakeBookingApp()
 	// create a structure to be populated
 	{
 		d, _ := json.Marshal(map[string]int{"a": 1})
@@ -194,6 +196,7 @@ func TestJsonBinder(t *testing.T) {
 }
 
 func TestBinder(t *testing.T) {
+	startFakeBookingApp()
 	// Reuse the mvc_test.go multipart request to test the binder.
 	params := &Params{}
 	ParseParams(params, NewRequest(getMultipartRequest()))
@@ -400,3 +403,54 @@ func init() {
 	DateTimeFormat = DefaultDateTimeFormat
 	TimeFormats = append(TimeFormats, DefaultDateFormat, DefaultDateTimeFormat, "01/02/2006")
 }
+
+func TestHeaderBinder(t *testing.T) {
+	startFakeBookingApp()
+	type request struct {
+		Tenant string `header:"X-Tenant-Id"`
+		Limit  int    `header:"X-Limit"`
+		Name   string
+	}
+	r := httptest.NewRequest("GET", "/?req.Name=bob&req.Tenant=ignored", nil)
+	r.Header.Set("X-Tenant-Id", "acme")
+	r.Header.Set("X-Limit", "25")
+	params := &Params{}
+	ParseParams(params, NewRequest(r))
+
+	actual := Bind(params, "req", reflect.TypeOf(request{})).Interface().(request)
+	eq(t, "string header", actual.Tenant, "acme")
+	eq(t, "int header", actual.Limit, 25)
+	eq(t, "query param", actual.Name, "bob")
+
+	// Headers bind into JSON bodies too
+	params = &Params{JSON: []byte(`{"Name":"alice","Limit":3}`)}
+	ParseParams(params, NewRequest(r))
+	actual = Bind(params, "req", reflect.TypeOf(request{})).Interface().(request)
+	eq(t, "json name", actual.Name, "alice")
+	eq(t, "json int header", actual.Limit, 25)
+}
+
+func TestDuplicateParams(t *testing.T) {
+	startFakeBookingApp()
+	defer Config.SetOption("binding.duplicates", "first")
+	params := &Params{}
+	ParseParams(params, NewRequest(httptest.NewRequest("GET", "/?id=1&id=2&id=3", nil)))
+
+	eq(t, "scalar first", Bind(params, "id", reflect.TypeOf(0)).Interface(), 1)
+	Config.SetOption("binding.duplicates", "last")
+	eq(t, "scalar last", Bind(params, "id", reflect.TypeOf(0)).Interface(), 3)
+	eq(t, "slice", fmt.Sprint(Bind(params, "id", reflect.TypeOf([]int{})).Interface()), "[1 2 3]")
+
+	params = &Params{}
+	ParseParams(params, NewRequest(httptest.NewRequest("GET", "/?id=1", nil)))
+	eq(t, "single value slice", fmt.Sprint(Bind(params, "id", reflect.TypeOf([]int{})).Interface()), "[1]")
+
+	params = &Params{}
+	ParseParams(params, NewRequest(httptest.NewRequest("GET", "/?id=1&id=x", nil)))
+	eq(t, "slice without the invalid value", fmt.Sprint(Bind(params, "id", reflect.TypeOf([]int{})).Interface()), "[1]")
+	if len(params.bindErrors) != 1 {
+		t.Fatalf("Expected a BindError for the invalid value, got %v", params.bindErrors)
+	}
+	eq(t, "bind error field", params.bindErrors[0].Field, "id")
+	eq(t, "bind error value", params.bindErrors[0].Value, "x")
+}
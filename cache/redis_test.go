@@ -17,7 +17,7 @@ import (
 const redisTestServer = "localhost:6379"
 
 var newRedisCache = func(t *testing.T, defaultExpiration time.Duration) Cache {
-	revel.Config = config.NewContext()
+	revel.Config = revel.NewConfigContext(config.NewContext())
 
 	c, err := net.Dial("tcp", redisTestServer)
 	if err == nil {
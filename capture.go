@@ -0,0 +1,125 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Response capture lets the filters inspect and modify the response body of
+// the action, enabled in app.conf:
+//
+//	response.capture         = true
+//	response.capture.maxsize = 1048576
+//
+// The ActionInvoker then applies the result to a buffer, so once the chain
+// below a filter has returned the body is in Response.CapturedBody. The
+// buffer is sent after all the filters have returned. A body growing beyond
+// the maximum size (in bytes) is sent as it is written instead, and is no
+// longer captured.
+
+// captureResponseWriter buffers the status and body of a response until it is
+// flushed, up to the maximum size.
+type captureResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	written int // The length of the body written, before the filters change it
+	maxSize int
+	passing bool // Writing straight to the ResponseWriter
+}
+
+func newCaptureResponseWriter(w http.ResponseWriter) *captureResponseWriter {
+	return &captureResponseWriter{
+		ResponseWriter: w,
+		maxSize:        Config.IntDefault("response.capture.maxsize", 1<<20),
+	}
+}
+
+func (w *captureResponseWriter) WriteHeader(status int) {
+	if w.passing {
+		w.ResponseWriter.WriteHeader(status)
+	} else if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *captureResponseWriter) Write(b []byte) (int, error) {
+	if w.passing {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body.Len()+len(b) > w.maxSize {
+		TRACE.Printf("Response exceeds response.capture.maxsize of %d bytes, no longer capturing", w.maxSize)
+		w.flush(w.ResponseWriter)
+		return w.ResponseWriter.Write(b)
+	}
+	w.written += len(b)
+	return w.body.Write(b)
+}
+
+// reset discards what has been captured, for another result to replace it.
+func (w *captureResponseWriter) reset() {
+	if !w.passing {
+		w.status = 0
+		w.written = 0
+		w.body.Reset()
+	}
+}
+
+// flush writes what has been captured to out, which writes to the
+// ResponseWriter eventually, and stops capturing.
+func (w *captureResponseWriter) flush(out http.ResponseWriter) {
+	if w.passing {
+		return
+	}
+	w.passing = true
+	if w.status == 0 {
+		return
+	}
+	// The filters may have changed the length of the body. A HEAD request
+	// has none, and keeps the length of the body it would have had.
+	if w.Header().Get("Content-Length") != "" && (w.written > 0 || w.body.Len() > 0) {
+		w.Header().Set("Content-Length", strconv.Itoa(w.body.Len()))
+	}
+	out.WriteHeader(w.status)
+	if _, err := w.body.WriteTo(out); err != nil {
+		ERROR.Println("Response write failed:", err)
+	}
+}
+
+// Flush stops capturing, since the response is being streamed, and flushes
+// what has been captured.
+func (w *captureResponseWriter) Flush() {
+	w.flush(w.ResponseWriter)
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes what has been captured and closes the ResponseWriter, e.g.
+// the one of a HEAD request holding back the header.
+func (w *captureResponseWriter) Close() error {
+	w.flush(w.ResponseWriter)
+	if closer, ok := w.ResponseWriter.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// CapturedBody returns the buffered body of the response with
+// response.capture, which filters may modify. It is nil otherwise, or once
+// the body has outgrown the buffer.
+func (resp *Response) CapturedBody() *bytes.Buffer {
+	if resp.capture == nil || resp.capture.passing {
+		return nil
+	}
+	return &resp.capture.body
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseCapture(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("response.capture", "true")
+	filters := Filters
+	defer func() { Filters = filters }()
+	var captured string
+	Filters = []Filter{
+		RouterFilter,
+		ParamsFilter,
+		func(c *Controller, fc []Filter) {
+			fc[0](c, fc[1:])
+			captured = ""
+			if body := c.Response.CapturedBody(); body != nil {
+				captured = body.String()
+				body.Reset()
+				body.WriteString(strings.Replace(captured, "View hotel", "Hotel details", 1))
+			}
+		},
+		ActionInvoker,
+	}
+
+	resp := httptest.NewRecorder()
+	handle(resp, showRequest)
+	eq(t, "status", resp.Code, http.StatusOK)
+	if !strings.Contains(captured, "<h1>View hotel</h1>") {
+		t.Errorf("Expected the filter to read the rendered body, got %q", captured)
+	}
+	if !strings.Contains(resp.Body.String(), "<h1>Hotel details</h1>") {
+		t.Errorf("Expected the body modified by the filter, got %q", resp.Body.String())
+	}
+
+	// A body beyond the buffer is sent as it is
+	Config.SetOption("response.capture.maxsize", "64")
+	resp = httptest.NewRecorder()
+	handle(resp, showRequest)
+	eq(t, "uncaptured status", resp.Code, http.StatusOK)
+	eq(t, "uncaptured body seen", captured, "")
+	if !strings.Contains(resp.Body.String(), "<h1>View hotel</h1>") {
+		t.Errorf("Expected the whole body, got %q", resp.Body.String())
+	}
+}
+
+// Test that a HEAD request with response.capture gets the status and the
+// Content-Length of the GET request.
+func TestResponseCaptureHead(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("response.capture", "true")
+	defer Config.SetOption("response.capture", "false")
+
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/hotels/3", nil))
+	length := resp.Header().Get("Content-Length")
+	if length == "" {
+		t.Fatal("Expected the GET request to have a Content-Length")
+	}
+
+	resp = httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("HEAD", "/hotels/3", nil))
+	eq(t, "status", resp.Code, http.StatusOK)
+	eq(t, "content length", resp.Header().Get("Content-Length"), length)
+	eq(t, "body", resp.Body.Len(), 0)
+
+	resp = httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("HEAD", "/nonexistent", nil))
+	eq(t, "missing status", resp.Code, http.StatusNotFound)
+}
@@ -5,6 +5,7 @@
 package revel
 
 import (
+	"bytes"
 	"io"
 	"net/http"
 	"strconv"
@@ -47,15 +48,28 @@ type CompressResponseWriter struct {
 	closeNotify     chan bool
 	parentNotify    <-chan bool
 	closed          bool
+
+	// With compress.minsize, the headers and the start of a response of an
+	// unknown length are held back until it is known to be large enough
+	minSize int
+	pending bool
+	status  int
+	buffer  bytes.Buffer
 }
 
 // CompressFilter does compresssion of response body in gzip/deflate if
-// `results.compressed=true` in the app.conf
+// `results.compressed=true` in the app.conf. Responses smaller than
+// compress.minsize bytes (0 by default) are sent uncompressed, as compressing
+// them gains little or even makes them larger.
 func CompressFilter(c *Controller, fc []Filter) {
 	fc[0](c, fc[1:])
 	if Config.BoolDefault("results.compressed", false) {
 		if c.Response.Status != http.StatusNoContent && c.Response.Status != http.StatusNotModified {
-			writer := CompressResponseWriter{c.Response.Out, nil, "", false, make(chan bool, 1), nil, false}
+			writer := CompressResponseWriter{
+				ResponseWriter: c.Response.Out,
+				closeNotify:    make(chan bool, 1),
+				minSize:        Config.IntDefault("compress.minsize", 0),
+			}
 			writer.DetectCompressionType(c.Request, c.Response)
 			w, ok := c.Response.Out.(http.CloseNotifier)
 			if ok {
@@ -75,37 +89,96 @@ func (c CompressResponseWriter) CloseNotify() <-chan bool {
 	return c.closeNotify
 }
 
-func (c *CompressResponseWriter) prepareHeaders() {
-	if c.compressionType != "" {
-		responseMime := c.Header().Get("Content-Type")
-		responseMime = strings.TrimSpace(strings.SplitN(responseMime, ";", 2)[0])
-		shouldEncode := false
-
-		if c.Header().Get("Content-Encoding") == "" {
-			for _, compressableMime := range compressableMimes {
-				if responseMime == compressableMime {
-					shouldEncode = true
-					c.Header().Set("Content-Encoding", c.compressionType)
-					c.Header().Del("Content-Length")
-					break
-				}
+// prepareHeaders decides whether the response is compressed. It returns true
+// if that depends on the length of the response, which is not known yet.
+func (c *CompressResponseWriter) prepareHeaders() bool {
+	responseMime := c.Header().Get("Content-Type")
+	responseMime = strings.TrimSpace(strings.SplitN(responseMime, ";", 2)[0])
+	shouldEncode := false
+
+	if c.Header().Get("Content-Encoding") == "" {
+		for _, compressableMime := range compressableMimes {
+			if responseMime == compressableMime {
+				// Whether it is compressed depends on the encodings accepted
+				appendVary(c.Header(), "Accept-Encoding")
+				shouldEncode = c.compressionType != ""
+				break
 			}
 		}
+	}
 
-		if !shouldEncode {
-			c.compressWriter = nil
-			c.compressionType = ""
+	if shouldEncode && c.minSize > 0 {
+		length, err := strconv.Atoi(c.Header().Get("Content-Length"))
+		if err != nil {
+			c.pending = true
+			return true
 		}
+		shouldEncode = length >= c.minSize
+	}
+	c.encode(shouldEncode)
+	return false
+}
+
+// encode sets up the response to be compressed or not.
+func (c *CompressResponseWriter) encode(shouldEncode bool) {
+	if shouldEncode {
+		c.Header().Set("Content-Encoding", c.compressionType)
+		c.Header().Del("Content-Length")
+	} else {
+		c.compressWriter = nil
+		c.compressionType = ""
+	}
+}
+
+// release sends the headers and the body held back while the length of the
+// response was unknown, compressed or not.
+func (c *CompressResponseWriter) release(shouldEncode bool) error {
+	c.pending = false
+	c.encode(shouldEncode)
+	if !shouldEncode {
+		c.Header().Set("Content-Length", strconv.Itoa(c.buffer.Len()))
 	}
+	c.ResponseWriter.WriteHeader(c.status)
+	_, err := c.Write(c.buffer.Bytes())
+	c.buffer.Reset()
+	return err
 }
 
 func (c *CompressResponseWriter) WriteHeader(status int) {
 	c.headersWritten = true
-	c.prepareHeaders()
+	if c.prepareHeaders() {
+		c.status = status
+		return
+	}
 	c.ResponseWriter.WriteHeader(status)
 }
 
+// Flush flushes the compressed data written so far to the client. A response
+// held back for compress.minsize is compressed, as it is being streamed.
+func (c *CompressResponseWriter) Flush() {
+	if c.pending {
+		// A streamed response which is not yet complete is likely to be large
+		if err := c.release(true); err != nil {
+			ERROR.Println("Flush failed:", err)
+		}
+	}
+	if c.compressionType != "" {
+		if err := c.compressWriter.Flush(); err != nil {
+			ERROR.Println("Flush failed:", err)
+		}
+	}
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func (c *CompressResponseWriter) Close() error {
+	// The complete response is smaller than compress.minsize
+	if c.pending {
+		if err := c.release(false); err != nil {
+			ERROR.Println("Response write failed:", err)
+		}
+	}
 	if c.compressionType != "" {
 		_ = c.compressWriter.Close()
 	}
@@ -136,8 +209,20 @@ func (c *CompressResponseWriter) Write(b []byte) (int, error) {
 		return 0, io.ErrClosedPipe
 	}
 	if !c.headersWritten {
-		c.prepareHeaders()
 		c.headersWritten = true
+		if c.prepareHeaders() {
+			c.status = http.StatusOK
+		}
+	}
+
+	if c.pending {
+		c.buffer.Write(b)
+		if c.buffer.Len() >= c.minSize {
+			if err := c.release(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
 	}
 
 	if c.compressionType != "" {
@@ -147,6 +232,31 @@ func (c *CompressResponseWriter) Write(b []byte) (int, error) {
 	return c.ResponseWriter.Write(b)
 }
 
+// acceptsEncoding reports whether the Accept-Encoding header permits the
+// encoding, by name or through "*", with a quality above zero.
+func acceptsEncoding(header, encoding string) bool {
+	wildcard := false
+	for _, accepted := range strings.Split(header, ",") {
+		parts := strings.SplitN(accepted, ";", 2)
+		name := strings.TrimSpace(parts[0])
+		q := 1.0
+		if len(parts) > 1 {
+			if param := strings.TrimSpace(parts[1]); strings.HasPrefix(param, "q=") {
+				if num, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = num
+				}
+			}
+		}
+		if strings.EqualFold(name, encoding) {
+			return q > 0
+		}
+		if name == "*" {
+			wildcard = q > 0
+		}
+	}
+	return wildcard
+}
+
 // DetectCompressionType method detects the comperssion type
 // from header "Accept-Encoding"
 func (c *CompressResponseWriter) DetectCompressionType(req *Request, resp *Response) {
@@ -226,3 +336,58 @@ func (c *CompressResponseWriter) DetectCompressionType(req *Request, resp *Respo
 		}
 	}
 }
+
+// DecompressRequestFilter decompresses request bodies sent with a gzip or
+// deflate Content-Encoding, so they are bound like uncompressed ones. Other
+// encodings are refused with 415 Unsupported Media Type. The decompressed
+// body is limited by http.maxrequestsize like the raw body.
+func DecompressRequestFilter(c *Controller, fc []Filter) {
+	encoding := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || c.Request.Body == nil {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+	switch encoding {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(c.Request.Body)
+	case "deflate":
+		reader, err = zlib.NewReader(c.Request.Body)
+	default:
+		c.Result = c.Abort(http.StatusUnsupportedMediaType, "Unsupported Content-Encoding: "+encoding)
+		return
+	}
+	if err != nil {
+		c.Result = c.Abort(http.StatusBadRequest, "Failed to decompress the request body: "+err.Error())
+		return
+	}
+
+	var body io.ReadCloser = decompressedBody{reader, c.Request.Body}
+	if maxRequestSize := int64(Config.IntDefault("http.maxrequestsize", 0)); maxRequestSize > 0 {
+		body = http.MaxBytesReader(c.Response.Out, body, maxRequestSize)
+	}
+	c.Request.Body = body
+	c.Request.ContentLength = -1
+	c.Request.Header.Del("Content-Encoding")
+	c.Request.Header.Del("Content-Length")
+	fc[0](c, fc[1:])
+}
+
+// decompressedBody reads a request body through its decompressing reader,
+// closing both.
+type decompressedBody struct {
+	io.ReadCloser
+	body io.Closer
+}
+
+func (b decompressedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if bodyErr := b.body.Close(); err == nil {
+		err = bodyErr
+	}
+	return err
+}
@@ -5,7 +5,16 @@
 package revel
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -59,3 +68,188 @@ func BenchmarkRenderUnCompressed(b *testing.B) {
 		hotels.Show(3).Apply(c.Request, c.Response)
 	}
 }
+
+func TestPrecompressedFile(t *testing.T) {
+	startFakeBookingApp()
+	dir, err := ioutil.TempDir("", "revel-static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.js")
+	if err = ioutil.WriteFile(path, []byte("var plain = true;"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	_, _ = w.Write([]byte("var gzipped = true;"))
+	_ = w.Close()
+	if err = ioutil.WriteFile(path+".gz", gzipped.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/public/app.js", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(r), NewResponse(resp))
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.RenderFile(file, Inline).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := serve("gzip, deflate")
+	eq(t, "gzip encoding", resp.Header().Get("Content-Encoding"), "gzip")
+	eq(t, "gzip content type", resp.Header().Get("Content-Type"), "application/javascript")
+	eq(t, "gzip vary", resp.Header().Get("Vary"), "Accept-Encoding")
+	eq(t, "gzip body", resp.Body.String(), gzipped.String())
+
+	resp = serve("gzip;q=0, deflate")
+	eq(t, "plain encoding", resp.Header().Get("Content-Encoding"), "")
+	eq(t, "plain vary", resp.Header().Get("Vary"), "Accept-Encoding")
+	eq(t, "plain body", resp.Body.String(), "var plain = true;")
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	eq(t, "plain", acceptsEncoding("gzip", "gzip"), true)
+	eq(t, "quality", acceptsEncoding("deflate, gzip;q=0.5", "gzip"), true)
+	eq(t, "refused", acceptsEncoding("gzip;q=0, *", "gzip"), false)
+	eq(t, "wildcard", acceptsEncoding("*", "gzip"), true)
+	eq(t, "missing", acceptsEncoding("deflate", "gzip"), false)
+	eq(t, "empty", acceptsEncoding("", "gzip"), false)
+}
+
+func TestDecompressRequestFilter(t *testing.T) {
+	startFakeBookingApp()
+	filters := Filters
+	defer func() { Filters = filters }()
+	var bound string
+	Filters = []Filter{DecompressRequestFilter, ParamsFilter, func(c *Controller, fc []Filter) {
+		bound = c.Params.Get("name")
+		c.Result = c.NoContent()
+	}}
+
+	post := func(encoding string, body []byte) int {
+		bound = ""
+		r := httptest.NewRequest("POST", "/hotels/3", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Content-Encoding", encoding)
+		resp := httptest.NewRecorder()
+		handle(resp, r)
+		return resp.Code
+	}
+	compress := func(newWriter func(io.Writer) io.WriteCloser, form string) []byte {
+		var b bytes.Buffer
+		w := newWriter(&b)
+		_, _ = w.Write([]byte(form))
+		_ = w.Close()
+		return b.Bytes()
+	}
+	gzipped := func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	deflated := func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }
+	form := url.Values{"name": {"Grand Hotel"}}.Encode()
+
+	eq(t, "gzip status", post("gzip", compress(gzipped, form)), http.StatusNoContent)
+	eq(t, "gzip bound", bound, "Grand Hotel")
+	eq(t, "deflate status", post("deflate", compress(deflated, form)), http.StatusNoContent)
+	eq(t, "deflate bound", bound, "Grand Hotel")
+	eq(t, "plain status", post("", []byte(form)), http.StatusNoContent)
+	eq(t, "plain bound", bound, "Grand Hotel")
+	eq(t, "unsupported encoding", post("br", []byte(form)), http.StatusUnsupportedMediaType)
+	eq(t, "corrupt body", post("gzip", []byte(form)), http.StatusBadRequest)
+
+	// The limit applies to the decompressed size, which is larger here
+	large := url.Values{"name": {strings.Repeat("x", 4096)}}.Encode()
+	Config.SetOption("http.maxrequestsize", "1024")
+	body := compress(gzipped, large)
+	if len(body) > 1024 {
+		t.Fatalf("Expected the compressed body to fit the limit, it is %d bytes", len(body))
+	}
+	post("gzip", body)
+	eq(t, "bound beyond the limit", bound, "")
+}
+
+func TestAppendVary(t *testing.T) {
+	resp := NewResponse(httptest.NewRecorder())
+	resp.AppendVary("Accept")
+	resp.AppendVary("accept-encoding")
+	resp.AppendVary("Accept")
+	resp.AppendVary("Accept-Encoding")
+	eq(t, "appended", resp.Out.Header().Get("Vary"), "Accept, Accept-Encoding")
+
+	resp.Out.Header()["Vary"] = []string{"Cookie", "Origin, Accept"}
+	resp.AppendVary("accept")
+	resp.AppendVary("Accept-Language")
+	eq(t, "merged", strings.Join(resp.Out.Header()["Vary"], "|"), "Cookie, Origin, Accept, Accept-Language")
+
+	resp.Out.Header().Set("Vary", "*")
+	resp.AppendVary("Accept")
+	eq(t, "any", resp.Out.Header().Get("Vary"), "*")
+}
+
+func TestCompressedRenderVary(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.compressed", "true")
+	for _, acceptEncoding := range []string{"gzip", ""} {
+		r := httptest.NewRequest("GET", "/hotels/3", nil)
+		r.Header.Set("Accept", "text/html")
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		resp := httptest.NewRecorder()
+		handle(resp, r)
+		eq(t, "status", resp.Code, http.StatusOK)
+		eq(t, "vary with "+acceptEncoding, strings.Join(resp.Header()["Vary"], "|"), "Accept, Accept-Encoding")
+	}
+}
+
+func TestCompressMinSize(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.compressed", "true")
+	Config.SetOption("compress.minsize", "1024")
+	defer func() {
+		Config.SetOption("results.compressed", "false")
+		Config.SetOption("compress.minsize", "0")
+	}()
+	filters := Filters
+	defer func() { Filters = filters }()
+	var result func(c *Controller) Result
+	Filters = []Filter{CompressFilter, func(c *Controller, fc []Filter) {
+		c.Result = result(c)
+	}}
+	get := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/hotels", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		resp := httptest.NewRecorder()
+		handle(resp, r)
+		return resp
+	}
+	gunzip := func(body []byte) string {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, _ := ioutil.ReadAll(reader)
+		return string(plain)
+	}
+
+	// Responses of an unknown length
+	small, large := "small", strings.Repeat("large ", 512)
+	result = func(c *Controller) Result { return c.RenderText(small) }
+	resp := get()
+	eq(t, "small encoding", resp.Header().Get("Content-Encoding"), "")
+	eq(t, "small length", resp.Header().Get("Content-Length"), "5")
+	eq(t, "small body", resp.Body.String(), small)
+
+	result = func(c *Controller) Result { return c.RenderText(large) }
+	resp = get()
+	eq(t, "large encoding", resp.Header().Get("Content-Encoding"), "gzip")
+	eq(t, "large body", gunzip(resp.Body.Bytes()), large)
+
+	// Responses with a Content-Length
+	result = func(c *Controller) Result { return c.RenderTemplate("hotels/confirmation.txt") }
+	resp = get()
+	eq(t, "template encoding", resp.Header().Get("Content-Encoding"), "")
+	eq(t, "template body", resp.Body.String(), "Booking confirmed: \n")
+}
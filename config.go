@@ -0,0 +1,242 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/revel/config"
+)
+
+// ConfigContext is the application configuration loaded from app.conf.
+// It wraps the config.Context of the selected run mode and layers values
+// from outside the config files on top of it.
+//
+// Precedence of the accessors, highest first:
+//  1. Overrides set by SetOverride
+//  2. Environment variables, see ConfigEnvPrefix
+//  3. Values from the config files
+//  4. The default passed to the accessor
+//
+// The accessors are safe to use while ReloadConfig replaces the context of
+// the config files. Only the accessors below are provided, the others of
+// config.Context would skip the overrides and the environment; Raw gives
+// access to the config files.
+type ConfigContext struct {
+	context *config.Context
+	lock    sync.RWMutex // Guards the context, which ReloadConfig replaces
+}
+
+var (
+	// ConfigEnvPrefix is the prefix of environment variables which are
+	// consulted by the config accessors. The variable for a key is the prefix
+	// followed by the uppercased key with dots replaced by underscores, e.g.
+	// REVEL_HTTP_PORT for http.port. An empty prefix disables the lookup.
+	ConfigEnvPrefix = "REVEL_"
+
+	// Process-lifetime overrides, these survive reloading of the config files
+	configOverrides     = map[string]string{}
+	configOverridesLock sync.RWMutex
+
+	// Functions invoked after the config files have been reloaded
+	configReloadHooks []func()
+
+	// Accepted boolean values, matching the config package
+	configBools = map[string]bool{
+		"1": true, "t": true, "true": true, "y": true, "yes": true, "on": true,
+		"0": false, "f": false, "false": false, "n": false, "no": false, "off": false,
+	}
+)
+
+// NewConfigContext wraps a loaded config.Context.
+func NewConfigContext(context *config.Context) *ConfigContext {
+	return &ConfigContext{context: context}
+}
+
+// replace makes the context the one of the config files.
+func (c *ConfigContext) replace(context *config.Context) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.context = context
+}
+
+// SetOverride sets the value of key for the lifetime of the process, taking
+// precedence over the config files. It may be called before Init, so that
+// a main can inject values (e.g. from the environment) before Run reads them.
+func (c *ConfigContext) SetOverride(key, value string) {
+	configOverridesLock.Lock()
+	defer configOverridesLock.Unlock()
+	configOverrides[key] = value
+}
+
+// RemoveOverride removes an override set by SetOverride.
+func (c *ConfigContext) RemoveOverride(key string) {
+	configOverridesLock.Lock()
+	defer configOverridesLock.Unlock()
+	delete(configOverrides, key)
+}
+
+// lookup returns the value of key which is set outside of the config files.
+func (c *ConfigContext) lookup(key string) (string, bool) {
+	configOverridesLock.RLock()
+	value, found := configOverrides[key]
+	configOverridesLock.RUnlock()
+	if found || ConfigEnvPrefix == "" {
+		return value, found
+	}
+	return os.LookupEnv(configEnvName(key))
+}
+
+// configEnvName returns the environment variable name of key.
+func configEnvName(key string) string {
+	return ConfigEnvPrefix + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// String returns the string value of key.
+func (c *ConfigContext) String(key string) (string, bool) {
+	if value, found := c.lookup(key); found {
+		return value, true
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.context.String(key)
+}
+
+// StringDefault returns the string value of key, or dfault if it is not set.
+func (c *ConfigContext) StringDefault(key, dfault string) string {
+	if value, found := c.String(key); found {
+		return value
+	}
+	return dfault
+}
+
+// Int returns the int value of key.
+func (c *ConfigContext) Int(key string) (int, bool) {
+	if value, found := c.lookup(key); found {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i, true
+		}
+		WARN.Printf("Config: ignoring %s, %q is not an int", key, value)
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.context.Int(key)
+}
+
+// IntDefault returns the int value of key, or dfault if it is not set.
+func (c *ConfigContext) IntDefault(key string, dfault int) int {
+	if value, found := c.Int(key); found {
+		return value
+	}
+	return dfault
+}
+
+// Bool returns the bool value of key.
+func (c *ConfigContext) Bool(key string) (bool, bool) {
+	if value, found := c.lookup(key); found {
+		if b, ok := configBools[strings.ToLower(value)]; ok {
+			return b, true
+		}
+		WARN.Printf("Config: ignoring %s, %q is not a bool", key, value)
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.context.Bool(key)
+}
+
+// BoolDefault returns the bool value of key, or dfault if it is not set.
+func (c *ConfigContext) BoolDefault(key string, dfault bool) bool {
+	if value, found := c.Bool(key); found {
+		return value
+	}
+	return dfault
+}
+
+// SetOption sets the value of key, until the config files are reloaded.
+func (c *ConfigContext) SetOption(key, value string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.context.SetOption(key, value)
+}
+
+// SetSection selects the section of the config files the values are read
+// from, e.g. the run mode.
+func (c *ConfigContext) SetSection(section string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.context.SetSection(section)
+}
+
+// HasSection reports whether the config files have the section.
+func (c *ConfigContext) HasSection(section string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.context.HasSection(section)
+}
+
+// Options returns the keys starting with prefix of the config files and the
+// overrides. The environment variables are not listed, their names don't map
+// back to keys.
+func (c *ConfigContext) Options(prefix string) []string {
+	c.lock.RLock()
+	options := c.context.Options(prefix)
+	c.lock.RUnlock()
+
+	configOverridesLock.RLock()
+	defer configOverridesLock.RUnlock()
+	var overridden []string
+	for key := range configOverrides {
+		if strings.HasPrefix(key, prefix) && !ContainsString(options, key) {
+			overridden = append(overridden, key)
+		}
+	}
+	sort.Strings(overridden)
+	return append(options, overridden...)
+}
+
+// Raw returns the config files of the current context.
+func (c *ConfigContext) Raw() *config.Config {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.context.Raw()
+}
+
+// OnConfigReload registers a function which is invoked after the config files
+// have been re-read by ReloadConfig, so that components caching config values
+// can refresh them.
+func OnConfigReload(f func()) {
+	configReloadHooks = append(configReloadHooks, f)
+}
+
+// ReloadConfig re-reads app.conf for the current run mode and invokes the
+// functions registered with OnConfigReload. The current config is kept if
+// the files can not be read. Overrides are kept as well.
+func ReloadConfig() error {
+	context, err := config.LoadContext("app.conf", ConfPaths)
+	if err != nil {
+		return err
+	}
+	mode := RunMode
+	if mode == "" {
+		mode = config.DefaultSection
+	}
+	if !context.HasSection(mode) {
+		return fmt.Errorf("app.conf: No mode found: %s", mode)
+	}
+	context.SetSection(mode)
+	// Replace the files of the config in place, it is in use by the requests
+	Config.replace(context)
+
+	INFO.Println("Config reloaded")
+	for _, f := range configReloadHooks {
+		f()
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestConfigOverride(t *testing.T) {
+	defer Config.RemoveOverride("http.port")
+	defer Config.RemoveOverride("app.name")
+	defer Config.RemoveOverride("http.ssl")
+	defer Config.RemoveOverride("app.extra")
+
+	// Overrides may be set before the config is loaded by Init
+	Config.SetOverride("http.port", "9100")
+	startFakeBookingApp()
+	eq(t, "http port", 9100, HTTPPort)
+
+	Config.SetOverride("app.name", "Overridden")
+	Config.SetOverride("http.ssl", "true")
+	eq(t, "string override", "Overridden", Config.StringDefault("app.name", ""))
+	eq(t, "int override", 9100, Config.IntDefault("http.port", 0))
+	eq(t, "bool override", true, Config.BoolDefault("http.ssl", false))
+
+	Config.SetOverride("app.extra", "extra")
+	options := Config.Options("app.")
+	eq(t, "overridden option listed", true, ContainsString(options, "app.extra"))
+	names := 0
+	for _, option := range options {
+		if option == "app.name" {
+			names++
+		}
+	}
+	eq(t, "option in both listed once", 1, names)
+
+	Config.RemoveOverride("app.name")
+	eq(t, "file value", "Booking example", Config.StringDefault("app.name", ""))
+	eq(t, "default value", "default", Config.StringDefault("app.missing", "default"))
+
+	// An unparsable override falls back to the file value
+	Config.SetOverride("http.port", "port")
+	eq(t, "invalid int override", 9000, Config.IntDefault("http.port", 0))
+}
+
+func TestConfigEnvironment(t *testing.T) {
+	startFakeBookingApp()
+	defer os.Unsetenv("REVEL_APP_NAME")
+	defer os.Unsetenv("REVEL_HTTP_PORT")
+	defer os.Unsetenv("REVEL_APP_MISSING")
+	defer Config.RemoveOverride("app.name")
+
+	eq(t, "env name", "REVEL_HTTP_TIMEOUT_READ", configEnvName("http.timeout.read"))
+
+	// The environment beats the file and the default
+	os.Setenv("REVEL_HTTP_PORT", "9200")
+	os.Setenv("REVEL_APP_MISSING", "from env")
+	eq(t, "env over file", 9200, Config.IntDefault("http.port", 0))
+	eq(t, "env over default", "from env", Config.StringDefault("app.missing", "default"))
+
+	// An override beats the environment
+	os.Setenv("REVEL_APP_NAME", "from env")
+	Config.SetOverride("app.name", "from override")
+	eq(t, "override over env", "from override", Config.StringDefault("app.name", ""))
+
+	// An empty prefix disables the environment lookup
+	ConfigEnvPrefix = ""
+	defer func() { ConfigEnvPrefix = "REVEL_" }()
+	eq(t, "env disabled", 9000, Config.IntDefault("http.port", 0))
+}
+
+func TestConfigReload(t *testing.T) {
+	startFakeBookingApp()
+	hooks := configReloadHooks
+	defer func() { configReloadHooks = hooks }()
+
+	var reloaded bool
+	OnConfigReload(func() {
+		reloaded = true
+		eq(t, "reloaded value", "Booking example", Config.StringDefault("app.name", ""))
+	})
+
+	Config.SetOption("app.name", "Changed")
+	if err := ReloadConfig(); err != nil {
+		t.Fatalf("Failed to reload config: %s", err)
+	}
+	if !reloaded {
+		t.Error("Expected the reload callback to be invoked")
+	}
+}
+
+// Test that requests can read the config while it is reloaded, for go test
+// -race to check.
+func TestConfigReloadConcurrent(t *testing.T) {
+	startFakeBookingApp()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			if err := ReloadConfig(); err != nil {
+				t.Errorf("Failed to reload config: %s", err)
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			eq(t, "app name", Config.StringDefault("app.name", ""), "Booking example")
+			return
+		default:
+			_ = Config.StringDefault("app.name", "")
+			addResponseHeaders(http.Header{})
+		}
+	}
+}
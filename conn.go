@@ -0,0 +1,173 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The connections of the server by remote address, so that a result can set
+// deadlines on the connection it is written to. Only TCP connections are
+// tracked, as the remote addresses of unix sockets are not unique.
+var (
+	serverConns     = map[string]net.Conn{}
+	serverConnsLock sync.RWMutex
+)
+
+// trackConn is the ConnState hook of the server maintaining serverConns.
+func trackConn(conn net.Conn, state http.ConnState) {
+	if _, ok := conn.RemoteAddr().(*net.TCPAddr); !ok {
+		return
+	}
+	key := conn.RemoteAddr().String()
+	limitConnsPerIP(conn, key, state)
+
+	serverConnsLock.Lock()
+	defer serverConnsLock.Unlock()
+	switch state {
+	case http.StateNew:
+		serverConns[key] = conn
+	case http.StateHijacked, http.StateClosed:
+		delete(serverConns, key)
+	}
+}
+
+// The live connections by client IP, limited by server.maxconnperip. The
+// connections counted are kept by remote address, along with whether they
+// stay counted once hijacked (as websockets do) until released.
+var (
+	connsPerIP     = map[string]int{}
+	countedConns   = map[string]*countedConn{}
+	connsPerIPLock sync.Mutex
+)
+
+type countedConn struct {
+	ip       string
+	keep     bool
+	hijacked bool
+}
+
+// limitConnsPerIP counts the connections of each client IP, closing a new
+// connection straight away when the client already has server.maxconnperip
+// connections open.
+func limitConnsPerIP(conn net.Conn, key string, state http.ConnState) {
+	connsPerIPLock.Lock()
+	defer connsPerIPLock.Unlock()
+	switch state {
+	case http.StateNew:
+		limit := Config.IntDefault("server.maxconnperip", 0)
+		if limit <= 0 {
+			return
+		}
+		ip := conn.RemoteAddr().(*net.TCPAddr).IP.String()
+		if connsPerIP[ip] >= limit {
+			WARN.Printf("Refusing connection from %s: over server.maxconnperip of %d", ip, limit)
+			_ = conn.Close()
+			return
+		}
+		connsPerIP[ip]++
+		countedConns[key] = &countedConn{ip: ip}
+	case http.StateHijacked:
+		if counted, ok := countedConns[key]; ok && counted.keep {
+			counted.hijacked = true
+			return
+		}
+		releaseConn(key)
+	case http.StateClosed:
+		releaseConn(key)
+	}
+}
+
+// releaseConn stops counting the connection of the remote address. The
+// caller must hold connsPerIPLock.
+func releaseConn(key string) {
+	counted, ok := countedConns[key]
+	if !ok {
+		return
+	}
+	delete(countedConns, key)
+	if connsPerIP[counted.ip]--; connsPerIP[counted.ip] <= 0 {
+		delete(connsPerIP, counted.ip)
+	}
+}
+
+// keepConnCounted keeps the connection of the request counted against
+// server.maxconnperip while it is hijacked, until the returned function is
+// called. The server no longer reports the state of a hijacked connection.
+func keepConnCounted(r *http.Request) (release func()) {
+	connsPerIPLock.Lock()
+	defer connsPerIPLock.Unlock()
+	if counted, ok := countedConns[r.RemoteAddr]; ok {
+		counted.keep = true
+	}
+	return func() {
+		connsPerIPLock.Lock()
+		defer connsPerIPLock.Unlock()
+		if counted, ok := countedConns[r.RemoteAddr]; ok {
+			if counted.hijacked {
+				releaseConn(r.RemoteAddr)
+			} else {
+				counted.keep = false
+			}
+		}
+	}
+}
+
+// requestConn returns the connection the request was received on, or nil if
+// it is not known.
+func requestConn(r *http.Request) net.Conn {
+	serverConnsLock.RLock()
+	defer serverConnsLock.RUnlock()
+	return serverConns[r.RemoteAddr]
+}
+
+// deadlineResponseWriter extends the write deadline of the connection before
+// every write, so that writing fails once the client has not accepted any
+// data for the timeout.
+type deadlineResponseWriter struct {
+	http.ResponseWriter
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (w *deadlineResponseWriter) Write(b []byte) (int, error) {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.timeout)); err != nil {
+		return 0, err
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *deadlineResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// withWriteDeadline makes the writes to the response of the request time out
+// after the configured results.write.timeout (in seconds) without progress.
+// The returned function restores the response writer.
+func withWriteDeadline(req *Request, resp *Response) (restore func()) {
+	timeout := time.Duration(Config.IntDefault("results.write.timeout", 0)) * time.Second
+	conn := requestConn(req.Request)
+	if timeout <= 0 || conn == nil {
+		return func() {}
+	}
+
+	out := resp.Out
+	resp.Out = &deadlineResponseWriter{out, conn, timeout}
+	return func() {
+		resp.Out = out
+		// Back to the write timeout of the server for the rest of the response,
+		// it sets the deadline of the next request on the connection
+		var deadline time.Time
+		if server, ok := req.Context().Value(http.ServerContextKey).(*http.Server); ok && server.WriteTimeout > 0 {
+			deadline = time.Now().Add(server.WriteTimeout)
+		}
+		_ = conn.SetWriteDeadline(deadline)
+	}
+}
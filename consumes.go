@@ -0,0 +1,54 @@
+// Copyright (c) 2012-2016 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ConsumesFilter refuses request bodies of a content type the action doesn't
+// accept with 415 Unsupported Media Type, before they are bound. The accepted
+// types of an action are configured by its name or by its controller's, e.g.
+//
+//	consumes.Hotels = application/json
+//	consumes.Hotels.Upload = multipart/form-data, image/*
+//
+// Actions without either accept any content type.
+func ConsumesFilter(c *Controller, fc []Filter) {
+	if c.Request.ContentLength != 0 {
+		accepted := Config.StringDefault("consumes."+c.Action, "")
+		if accepted == "" {
+			accepted = Config.StringDefault("consumes."+c.Name, "")
+		}
+		// Request.ContentType defaults to text/html, a body without a type is refused
+		contentType := c.Request.ContentType
+		if c.Request.Header.Get("Content-Type") == "" {
+			contentType = ""
+		}
+		if accepted != "" && !consumes(accepted, contentType) {
+			c.Result = c.Abort(http.StatusUnsupportedMediaType,
+				"Unsupported Content-Type, expecting one of: "+accepted)
+			return
+		}
+	}
+	fc[0](c, fc[1:])
+}
+
+// consumes returns true if the comma separated accepted types include the
+// content type, accepting e.g. text/* for any text.
+func consumes(accepted, contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	for _, typ := range strings.Split(accepted, ",") {
+		typ = strings.ToLower(strings.TrimSpace(typ))
+		if typ == contentType || typ == "*/*" ||
+			(strings.HasSuffix(typ, "/*") && strings.HasPrefix(contentType, typ[:len(typ)-1])) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2012-2016 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConsumesFilter(t *testing.T) {
+	startFakeBookingApp()
+	post := func(contentType string) int {
+		r := httptest.NewRequest("POST", "/hotels/3", strings.NewReader(`{"Name": "A Hotel"}`))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		c := NewController(NewRequest(r), NewResponse(httptest.NewRecorder()))
+		if err := c.SetAction("Hotels", "Show"); err != nil {
+			t.Fatalf("SetAction failed: %s", err)
+		}
+		ConsumesFilter(c, []Filter{func(c *Controller, fc []Filter) {
+			c.Result = c.NoContent()
+		}})
+		c.Result.Apply(c.Request, c.Response)
+		return c.Response.Status
+	}
+
+	eq(t, "unconfigured", post("application/x-www-form-urlencoded"), http.StatusNoContent)
+
+	Config.SetOption("consumes.Hotels.Show", "application/json, text/*")
+	eq(t, "matching", post("application/json; charset=utf-8"), http.StatusNoContent)
+	eq(t, "matching wildcard", post("text/plain"), http.StatusNoContent)
+	eq(t, "mismatching", post("application/x-www-form-urlencoded"), http.StatusUnsupportedMediaType)
+	eq(t, "missing", post(""), http.StatusUnsupportedMediaType)
+
+	// The action's types take precedence over the controller's
+	Config.SetOption("consumes.Hotels", "application/xml")
+	eq(t, "action types", post("application/json"), http.StatusNoContent)
+	Config.SetOption("consumes.Hotels.Show", "")
+	eq(t, "controller types", post("application/xml"), http.StatusNoContent)
+	eq(t, "controller mismatch", post("application/json"), http.StatusUnsupportedMediaType)
+}
@@ -35,9 +35,10 @@ type Controller struct {
 	Flash      Flash                  // User cookie, cleared after 1 request.
 	Session    Session                // Session, stored in cookie, signed.
 	Params     *Params                // Parameters from URL and form (including multipart).
-	Args       map[string]interface{} // Per-request scratch space.
+	Args       map[string]interface{} // Per-request scratch space, see Set and Get.
 	ViewArgs   map[string]interface{} // Variables passed to the template.
 	Validation *Validation            // Data validation helpers
+	Layout     string                 // The template wrapping the rendered ones, see RenderTemplate.
 }
 
 // The map of controllers, controllers are mapped by using the namespace|controller_name as the key
@@ -57,6 +58,18 @@ func NewController(req *Request, resp *Response) *Controller {
 	}
 }
 
+// Set stores a value for the rest of the request, e.g. the user authenticated
+// by a filter for the action to use. The values are kept in Args, which is
+// allocated for every request, so nothing carries over to the next one.
+func (c *Controller) Set(key string, value interface{}) {
+	c.Args[key] = value
+}
+
+// Get returns the value stored by Set for the request, or nil if there is none.
+func (c *Controller) Get(key string) interface{} {
+	return c.Args[key]
+}
+
 // FlashParams serializes the contents of Controller.Params to the Flash
 // cookie.
 func (c *Controller) FlashParams() {
@@ -101,6 +114,10 @@ func (c *Controller) setStatusIfNil(status int) {
 // 2) How to call the RenderTemplate by building the following line
 // c.RenderTemplate(c.Name + "/" + c.MethodType.Name + "." + c.Request.Format)
 //
+// When the Accept header has none of the types of the formats, the template of
+// the negotiate.fallback format is rendered, html by default. With
+// negotiate.fallback=406 a 406 Not Acceptable error is returned instead.
+//
 // If you want your code to run faster it is recommended you add the template values directly
 // to the c.ViewArgs and call c.RenderTemplate directly
 func (c *Controller) Render(extraViewArgs ...interface{}) Result {
@@ -127,6 +144,17 @@ func (c *Controller) Render(extraViewArgs ...interface{}) Result {
 			"(Action", c.Action, ")")
 	}
 
+	// The template depends on the format accepted
+	c.Response.AppendVary("Accept")
+	if _, accepted := negotiateFormat(c.Request.Request); !accepted {
+		// None of the types accepted can be rendered. Either refuse the
+		// request, or render the negotiate.fallback format (html by default).
+		fallback := Config.StringDefault("negotiate.fallback", "html")
+		if fallback == "406" {
+			return c.Abort(http.StatusNotAcceptable, "None of the accepted types can be rendered: "+c.Request.Header.Get("Accept"))
+		}
+		c.Request.Format = fallback
+	}
 	return c.RenderTemplate(c.Name + "/" + c.MethodType.Name + "." + c.Request.Format)
 }
 
@@ -141,10 +169,71 @@ func (c *Controller) RenderTemplate(templatePath string) Result {
 	if err != nil {
 		return c.RenderError(err)
 	}
+	layout, err := c.layout(templatePath, lang)
+	if err != nil {
+		return c.RenderError(err)
+	}
+
+	return &RenderTemplateResult{
+		Template:   template,
+		Layout:     layout,
+		ViewArgs:   c.ViewArgs,
+		controller: c,
+	}
+}
+
+// RenderStream renders the template like RenderTemplate, but sends the
+// layout up to the content before rendering the template, so a heavy page
+// starts loading right away. It is only an error page for the errors of the
+// layout: a failure of the template cuts the response off.
+func (c *Controller) RenderStream(templatePath string) Result {
+	result := c.RenderTemplate(templatePath)
+	if r, ok := result.(*RenderTemplateResult); ok {
+		r.Stream = true
+	}
+	return result
+}
+
+// layout returns the layout template to wrap the template in, if any. It is
+// c.Layout, else the template.layout.<controller name> or the template.layout
+// setting. A layout only wraps the templates of its own file extension, so
+// an HTML layout leaves e.g. the .txt templates alone.
+func (c *Controller) layout(templatePath, lang string) (Template, error) {
+	name := c.Layout
+	if name == "" {
+		name = Config.StringDefault("template.layout."+c.Name, Config.StringDefault("template.layout", ""))
+	}
+	if name == "" || filepath.Ext(name) != filepath.Ext(templatePath) {
+		return nil, nil
+	}
+	return MainTemplateLoader.TemplateLang(name, lang)
+}
+
+// RenderPartial renders the template defined as name by a {{define}} block of
+// the template templatePath, using the current ViewArgs. The markup around
+// the block, like the header and footer of a page, is left out, so AJAX
+// requests can fetch just the fragment of the page they replace. The layout
+// is left out as well.
+func (c *Controller) RenderPartial(templatePath, name string) Result {
+	lang, _ := c.ViewArgs[CurrentLocaleViewArg].(string)
+	template, err := MainTemplateLoader.TemplateLang(templatePath, lang)
+	if err != nil {
+		return c.RenderError(err)
+	}
+	partials, ok := template.(PartialTemplate)
+	if !ok {
+		return c.RenderError(fmt.Errorf("Template %s does not support partial rendering", templatePath))
+	}
+	partial := partials.Partial(name)
+	if partial == nil {
+		return c.RenderError(fmt.Errorf("Template %s does not define %s", templatePath, name))
+	}
+	c.setStatusIfNil(http.StatusOK)
 
 	return &RenderTemplateResult{
-		Template: template,
-		ViewArgs: c.ViewArgs,
+		Template:   partial,
+		ViewArgs:   c.ViewArgs,
+		controller: c,
 	}
 }
 
@@ -155,6 +244,16 @@ func (c *Controller) RenderJSON(o interface{}) Result {
 	return RenderJSONResult{o, ""}
 }
 
+// RenderJSONWithStatus renders the object as JSON with the given status and
+// an "application/json; charset=utf-8" content type, e.g. for the body of an
+// API error.
+func (c *Controller) RenderJSONWithStatus(status int, o interface{}) Result {
+	c.Response.Status = status
+	c.Response.ContentType = "application/json; charset=utf-8"
+
+	return c.RenderJSON(o)
+}
+
 // RenderJSONP renders JSONP result using encoding/json.Marshal
 func (c *Controller) RenderJSONP(callback string, o interface{}) Result {
 	c.setStatusIfNil(http.StatusOK)
@@ -169,6 +268,24 @@ func (c *Controller) RenderXML(o interface{}) Result {
 	return RenderXMLResult{o}
 }
 
+// RenderNegotiated renders the object in the format the request accepts: a
+// content type added with RegisterCodec, else JSON or XML. Other formats
+// render the action's template, as Render does.
+func (c *Controller) RenderNegotiated(o interface{}) Result {
+	c.setStatusIfNil(http.StatusOK)
+
+	if codec, ok := acceptedCodec(c.Request.Header.Get("Accept")); ok {
+		return RenderCodecResult{o, codec}
+	}
+	switch c.Request.Format {
+	case "json":
+		return RenderJSONResult{o, ""}
+	case "xml":
+		return RenderXMLResult{o}
+	}
+	return c.Render()
+}
+
 // RenderText renders plaintext in response, printf style.
 func (c *Controller) RenderText(text string, objs ...interface{}) Result {
 	c.setStatusIfNil(http.StatusOK)
@@ -180,6 +297,23 @@ func (c *Controller) RenderText(text string, objs ...interface{}) Result {
 	return &RenderTextResult{finalText}
 }
 
+// RenderTextWithStatus renders plaintext, printf style, with the given status
+// and a "text/plain; charset=utf-8" content type.
+func (c *Controller) RenderTextWithStatus(status int, text string, objs ...interface{}) Result {
+	c.Response.Status = status
+	c.Response.ContentType = "text/plain; charset=utf-8"
+
+	return c.RenderText(text, objs...)
+}
+
+// NoContent returns an HTTP 204 No Content response without a body, e.g. for
+// a successful DELETE.
+func (c *Controller) NoContent() Result {
+	c.Response.Status = http.StatusNoContent
+
+	return NoContentResult{}
+}
+
 // RenderHTML renders html in response
 func (c *Controller) RenderHTML(html string) Result {
 	c.setStatusIfNil(http.StatusOK)
@@ -211,6 +345,21 @@ func (c *Controller) NotFound(msg string, objs ...interface{}) Result {
 	})
 }
 
+// Unauthorized returns an HTTP 401 Unauthorized response whose body is the
+// formatted string of msg and objs. The WWW-Authenticate header is left to
+// the caller.
+func (c *Controller) Unauthorized(msg string, objs ...interface{}) Result {
+	finalText := msg
+	if len(objs) > 0 {
+		finalText = fmt.Sprintf(msg, objs...)
+	}
+	c.Response.Status = http.StatusUnauthorized
+	return c.RenderError(&Error{
+		Title:       "Unauthorized",
+		Description: finalText,
+	})
+}
+
 // Forbidden returns an HTTP 403 Forbidden response whose body is the
 // formatted string of msg and objs.
 func (c *Controller) Forbidden(msg string, objs ...interface{}) Result {
@@ -225,12 +374,40 @@ func (c *Controller) Forbidden(msg string, objs ...interface{}) Result {
 	})
 }
 
+// Abort returns a response with the given HTTP status whose body is the
+// message, rendered in the format of the request like the other errors.
+func (c *Controller) Abort(status int, message string) Result {
+	c.Response.Status = status
+	return c.RenderError(&Error{
+		Title:       http.StatusText(status),
+		Description: message,
+	})
+}
+
+// PreconditionFailed returns an HTTP 412 Precondition Failed response whose
+// body is the formatted string of msg and objs, e.g. when Request.IfMatch
+// fails for a conditional update.
+func (c *Controller) PreconditionFailed(msg string, objs ...interface{}) Result {
+	finalText := msg
+	if len(objs) > 0 {
+		finalText = fmt.Sprintf(msg, objs...)
+	}
+	c.Response.Status = http.StatusPreconditionFailed
+	return c.RenderError(&Error{
+		Title:       "Precondition Failed",
+		Description: finalText,
+	})
+}
+
 // RenderFile returns a file, either displayed inline or downloaded
 // as an attachment. The name and size are taken from the file info.
+// If a gzipped copy of the file exists alongside it (e.g. app.js.gz for
+// app.js), the copy is sent instead to clients accepting gzip.
 func (c *Controller) RenderFile(file *os.File, delivery ContentDisposition) Result {
 	c.setStatusIfNil(http.StatusOK)
 
 	var (
+		name          = filepath.Base(file.Name())
 		modtime       = time.Now()
 		fileInfo, err = file.Stat()
 	)
@@ -240,7 +417,33 @@ func (c *Controller) RenderFile(file *os.File, delivery ContentDisposition) Resu
 	if fileInfo != nil {
 		modtime = fileInfo.ModTime()
 	}
-	return c.RenderBinary(file, filepath.Base(file.Name()), delivery, modtime)
+	if gzipped := c.precompressedFile(file); gzipped != nil {
+		_ = file.Close()
+		file = gzipped
+	}
+	return c.RenderBinary(file, name, delivery, modtime)
+}
+
+// precompressedFile returns the opened gzipped copy of the file if there is
+// one and the client accepts gzip, setting the headers of the encoding.
+func (c *Controller) precompressedFile(file *os.File) *os.File {
+	gzipped, err := os.Open(file.Name() + ".gz")
+	if err != nil {
+		return nil
+	}
+	if info, err := gzipped.Stat(); err != nil || !info.Mode().IsRegular() {
+		_ = gzipped.Close()
+		return nil
+	}
+
+	// The response depends on the encodings accepted, whichever is sent
+	c.Response.AppendVary("Accept-Encoding")
+	if !acceptsEncoding(c.Request.Header.Get("Accept-Encoding"), "gzip") {
+		_ = gzipped.Close()
+		return nil
+	}
+	c.Response.Out.Header().Set("Content-Encoding", "gzip")
+	return gzipped
 }
 
 // RenderBinary is like RenderFile() except that it instead of a file on disk,
@@ -260,6 +463,53 @@ func (c *Controller) RenderBinary(memfile io.Reader, filename string, delivery C
 	}
 }
 
+// Deadline returns the time by which the request should be answered, and
+// whether there is one, e.g. as set by the TimeoutFilter. Actions can check
+// it before starting on expensive work.
+func (c *Controller) Deadline() (time.Time, bool) {
+	return c.Request.Context().Deadline()
+}
+
+// RenderReader streams the reader to the client as content of the given type,
+// closing it once done if it is an io.Closer.
+func (c *Controller) RenderReader(contentType string, reader io.Reader) Result {
+	c.setStatusIfNil(http.StatusOK)
+	if contentType == "" {
+		contentType = DefaultFileContentType
+	}
+
+	return &ReaderResult{ContentType: contentType, Reader: reader}
+}
+
+// RenderAttachment streams the reader to the client as a download of the
+// given file name, which may contain non-ASCII characters.
+func (c *Controller) RenderAttachment(filename string, reader io.Reader) Result {
+	return c.RenderBinary(reader, filename, Attachment, time.Now())
+}
+
+// EarlyHints sends a 103 Early Hints response with the given Link headers,
+// e.g. `</public/css/app.css>; rel=preload; as=style`, so that the client can
+// start fetching them while the action is still working on the response. The
+// links are sent along with the final response as well. It does nothing for
+// HTTP/1.0 clients, which do not support informational responses, and only
+// adds the links to the final response when built with Go before 1.19,
+// whose server can't send them.
+func (c *Controller) EarlyHints(links []string) {
+	if len(links) == 0 || !c.Request.ProtoAtLeast(1, 1) || c.Request.Websocket != nil {
+		return
+	}
+	out := c.Response.informational
+	if out == nil {
+		out = c.Response.Out
+	}
+	for _, link := range links {
+		out.Header().Add("Link", link)
+	}
+	if informationalSupported {
+		writeEarlyHints(out)
+	}
+}
+
 // Redirect to an action or to a URL.
 //   c.Redirect(Controller.Action)
 //   c.Redirect("/controller/action")
@@ -0,0 +1,205 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestControllerRequestStore(t *testing.T) {
+	startFakeBookingApp()
+	filters := Filters
+	defer func() { Filters = filters }()
+
+	first := true
+	var seen interface{}
+	Filters = []Filter{
+		func(c *Controller, fc []Filter) {
+			if first {
+				c.Set("user", "alice")
+				first = false
+			}
+			fc[0](c, fc[1:])
+		},
+		func(c *Controller, fc []Filter) {
+			seen = c.Get("user")
+		},
+	}
+
+	handle(httptest.NewRecorder(), showRequest)
+	eq(t, "value set by the filter", seen, "alice")
+
+	handle(httptest.NewRecorder(), showRequest)
+	eq(t, "value on the next request", seen, nil)
+}
+
+func TestConditionalUpdate(t *testing.T) {
+	startFakeBookingApp()
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PUT", "/hotels/3", nil)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(r), NewResponse(resp))
+		if c.Request.IfMatch(`"v2"`) {
+			c.Result = c.NoContent()
+		} else {
+			c.Result = c.PreconditionFailed("Hotel %d has been modified", 3)
+		}
+		c.Result.Apply(c.Request, c.Response)
+		return resp
+	}
+
+	eq(t, "matching", update(`"v2"`).Code, http.StatusNoContent)
+	eq(t, "matching one of several", update(`"v1", "v2"`).Code, http.StatusNoContent)
+	eq(t, "any", update("*").Code, http.StatusNoContent)
+	eq(t, "absent", update("").Code, http.StatusNoContent)
+	eq(t, "non-matching", update(`"v1"`).Code, http.StatusPreconditionFailed)
+	eq(t, "weak", update(`W/"v2"`).Code, http.StatusPreconditionFailed)
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	r := httptest.NewRequest("GET", "/hotels/3", nil)
+	req := NewRequest(r)
+	eq(t, "absent", req.IfNoneMatch("v2"), false)
+	r.Header.Set("If-None-Match", `W/"v1", W/"v2"`)
+	eq(t, "matching", req.IfNoneMatch("v2"), true)
+	eq(t, "non-matching", req.IfNoneMatch(`"v3"`), false)
+}
+
+func TestAbort(t *testing.T) {
+	startFakeBookingApp()
+	abort := func(accept string, status int, message string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/hotels/3", nil)
+		r.Header.Set("Accept", accept)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(r), NewResponse(resp))
+		c.Abort(status, message).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	for _, status := range []int{http.StatusForbidden, http.StatusTooManyRequests} {
+		resp := abort("text/html", status, "Slow down <now>")
+		eq(t, "html status", resp.Code, status)
+		eq(t, "html content type", resp.Header().Get("Content-Type"), "text/html; charset=utf-8")
+		if body := resp.Body.String(); !strings.Contains(body, "Slow down &lt;now&gt;") {
+			t.Errorf("Expected the escaped message in the html body, got %q", body)
+		}
+
+		resp = abort("application/json", status, "Slow down")
+		eq(t, "json status", resp.Code, status)
+		eq(t, "json content type", resp.Header().Get("Content-Type"), "application/json")
+		var body struct{ Title, Description string }
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode %q: %s", resp.Body.String(), err)
+		}
+		eq(t, "json title", body.Title, http.StatusText(status))
+		eq(t, "json description", body.Description, "Slow down")
+	}
+}
+
+func TestEarlyHints(t *testing.T) {
+	startFakeBookingApp()
+	filters := Filters
+	defer func() { Filters = filters }()
+	Filters = []Filter{CompressFilter, func(c *Controller, fc []Filter) {
+		c.EarlyHints([]string{"</public/css/app.css>; rel=preload; as=style", "</public/js/app.js>; rel=preload; as=script"})
+		c.Result = c.RenderText("final")
+	}}
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+
+	var hints []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			hints = append(hints, fmt.Sprint(code, " ", strings.Join(header["Link"], ", ")))
+			return nil
+		},
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/hotels", nil)
+	resp, err := http.DefaultClient.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	eq(t, "early hints", strings.Join(hints, "; "),
+		"103 </public/css/app.css>; rel=preload; as=style, </public/js/app.js>; rel=preload; as=script")
+	eq(t, "final status", resp.StatusCode, http.StatusOK)
+	eq(t, "final body", string(body), "final")
+}
+
+// Test that without support for informational responses the early hints
+// only add their links to the final response.
+func TestEarlyHintsUnsupported(t *testing.T) {
+	startFakeBookingApp()
+	defer func(supported bool) { informationalSupported = supported }(informationalSupported)
+	informationalSupported = false
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(httptest.NewRequest("GET", "/hotels", nil)), NewResponse(resp))
+	c.EarlyHints([]string{"</public/css/app.css>; rel=preload; as=style"})
+	c.RenderText("final").Apply(c.Request, c.Response)
+
+	eq(t, "status", resp.Code, http.StatusOK)
+	eq(t, "body", resp.Body.String(), "final")
+	eq(t, "links", resp.Header().Get("Link"), "</public/css/app.css>; rel=preload; as=style")
+}
+
+func TestNegotiateFallback(t *testing.T) {
+	startFakeBookingApp()
+	defer Config.SetOption("negotiate.fallback", "html")
+	show := func(accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/hotels/3", nil)
+		r.Header.Set("Accept", accept)
+		resp := httptest.NewRecorder()
+		handle(resp, r)
+		return resp
+	}
+
+	resp := show("application/pdf")
+	eq(t, "default fallback status", resp.Code, http.StatusOK)
+	eq(t, "default fallback type", resp.Header().Get("Content-Type"), "text/html; charset=utf-8")
+
+	Config.SetOption("negotiate.fallback", "406")
+	resp = show("application/pdf")
+	eq(t, "406 status", resp.Code, http.StatusNotAcceptable)
+	resp = show("text/html")
+	eq(t, "acceptable status", resp.Code, http.StatusOK)
+
+	Config.SetOption("negotiate.fallback", "txt")
+	resp = show("application/pdf")
+	eq(t, "txt fallback type", resp.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	eq(t, "txt fallback body", resp.Body.String(), "View Hotel: A Hotel\n")
+}
+
+// Test that BytesRead counts the request body as it is read.
+func TestRequestBytesRead(t *testing.T) {
+	body := strings.Repeat("x", 10000)
+	req := NewRequest(httptest.NewRequest("POST", "/hotels/3/book", strings.NewReader(body)))
+	eq(t, "before reading", req.BytesRead(), int64(0))
+
+	buf := make([]byte, 4096)
+	n, err := req.Body.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "partially read", req.BytesRead(), int64(n))
+
+	if _, err = ioutil.ReadAll(req.Body); err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "fully read", req.BytesRead(), int64(len(body)))
+}
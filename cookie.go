@@ -0,0 +1,73 @@
+// Copyright (c) 2012-2016 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CookieOptions are the attributes of a cookie set with SetCookieValue. The
+// zero value gets the defaults: cookie.path (default "/"), cookie.domain,
+// cookie.samesite (default lax), HttpOnly, and Secure over TLS or with
+// cookie.secure.
+type CookieOptions struct {
+	Path     string
+	Domain   string
+	MaxAge   int       // In seconds, zero for a session cookie
+	Expires  time.Time // Zero for a session cookie
+	SameSite http.SameSite
+	Secure   *bool // Overrides the default when not nil
+	HTTPOnly *bool // Overrides the default when not nil
+}
+
+// SetCookieValue sets the named cookie with the options, filling in the
+// defaults for the options which are not set.
+func (c *Controller) SetCookieValue(name, value string, opts CookieOptions) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		MaxAge:   opts.MaxAge,
+		Expires:  opts.Expires,
+		SameSite: opts.SameSite,
+		Secure:   CookieSecure || c.Request.TLS != nil,
+		HttpOnly: true,
+	}
+	if cookie.Path == "" {
+		cookie.Path = Config.StringDefault("cookie.path", "/")
+	}
+	if cookie.Domain == "" {
+		cookie.Domain = CookieDomain
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = cookieSameSite(Config.StringDefault("cookie.samesite", "lax"))
+	}
+	if opts.Secure != nil {
+		cookie.Secure = *opts.Secure
+	}
+	if opts.HTTPOnly != nil {
+		cookie.HttpOnly = *opts.HTTPOnly
+	}
+	c.SetCookie(cookie)
+}
+
+// cookieSameSite returns the SameSite mode named by cookie.samesite.
+func cookieSameSite(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	case "lax":
+		return http.SameSiteLaxMode
+	case "", "default":
+		return http.SameSiteDefaultMode
+	}
+	WARN.Println("Unknown cookie.samesite, using the default mode:", mode)
+	return http.SameSiteDefaultMode
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2012-2016 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetCookieValue(t *testing.T) {
+	startFakeBookingApp()
+	setCookie := func(secureRequest bool, opts CookieOptions) *http.Cookie {
+		r := httptest.NewRequest("GET", "/hotels/3", nil)
+		if secureRequest {
+			r.TLS = &tls.ConnectionState{}
+		}
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(r), NewResponse(resp))
+		c.SetCookieValue("theme", "dark", opts)
+		cookies := (&http.Response{Header: resp.Header()}).Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("Expected one cookie, got %v", cookies)
+		}
+		return cookies[0]
+	}
+
+	cookie := setCookie(false, CookieOptions{})
+	eq(t, "value", cookie.Value, "dark")
+	eq(t, "default path", cookie.Path, "/")
+	eq(t, "default HttpOnly", cookie.HttpOnly, true)
+	eq(t, "default Secure over HTTP", cookie.Secure, false)
+	eq(t, "default SameSite", cookie.SameSite, http.SameSiteLaxMode)
+	eq(t, "default Secure over TLS", setCookie(true, CookieOptions{}).Secure, true)
+
+	no, yes := false, true
+	cookie = setCookie(true, CookieOptions{
+		Path:     "/account",
+		MaxAge:   60,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   &no,
+		HTTPOnly: &no,
+	})
+	eq(t, "path", cookie.Path, "/account")
+	eq(t, "max age", cookie.MaxAge, 60)
+	eq(t, "SameSite", cookie.SameSite, http.SameSiteStrictMode)
+	eq(t, "Secure", cookie.Secure, false)
+	eq(t, "HttpOnly", cookie.HttpOnly, false)
+	eq(t, "forced Secure over HTTP", setCookie(false, CookieOptions{Secure: &yes}).Secure, true)
+
+	Config.SetOption("cookie.path", "/app")
+	Config.SetOption("cookie.samesite", "strict")
+	cookie = setCookie(false, CookieOptions{})
+	eq(t, "configured path", cookie.Path, "/app")
+	eq(t, "configured SameSite", cookie.SameSite, http.SameSiteStrictMode)
+}
+
+func TestCookiePrefix(t *testing.T) {
+	Config.SetOverride("cookie.prefix", "APP1")
+	startFakeBookingApp()
+	defer func() {
+		Config.RemoveOverride("cookie.prefix")
+		startFakeBookingApp()
+	}()
+	filters := Filters
+	defer func() { Filters = filters }()
+	var restored string
+	Filters = []Filter{SessionFilter, FlashFilter, func(c *Controller, _ []Filter) {
+		restored = c.Session["user"]
+		c.Session["user"] = "alice"
+		c.Flash.Success("saved")
+		c.Result = c.RenderText("ok")
+	}}
+
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/hotels", nil))
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range (&http.Response{Header: resp.Header()}).Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	if cookies["APP1_SESSION"] == nil || cookies["APP1_FLASH"] == nil {
+		t.Fatalf("Expected the prefixed session and flash cookies, got %v", resp.Header()["Set-Cookie"])
+	}
+	if cookies["REVEL_SESSION"] != nil {
+		t.Error("Expected no cookie with the default prefix")
+	}
+
+	// The prefixed cookie is read back, the one of another app is not
+	r := httptest.NewRequest("GET", "/hotels", nil)
+	r.AddCookie(cookies["APP1_SESSION"])
+	handle(httptest.NewRecorder(), r)
+	eq(t, "restored session", restored, "alice")
+
+	r = httptest.NewRequest("GET", "/hotels", nil)
+	r.AddCookie(&http.Cookie{Name: "REVEL_SESSION", Value: cookies["APP1_SESSION"].Value})
+	handle(httptest.NewRecorder(), r)
+	eq(t, "session of another prefix", restored, "")
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Health check and drain endpoints for rolling deploys, served ahead of the
+// filter chain when enabled in app.conf:
+//
+//	server.health       = true
+//	server.health.path  = /_health
+//	server.ready        = true
+//	server.ready.path   = /_ready
+//	server.drain        = true
+//	server.drain.path   = /_drain
+//	server.drain.delay  = 10
+//
+// The health check reports whether the process is up, the readiness check
+// whether the app is ready for requests: it reports 503 until the app calls
+// SetReady(true), typically from a startup hook once its dependencies are
+// connected.
+//
+// A POST to the drain endpoint (or a call to Drain) makes the health and
+// readiness checks report 503, closes keep-alive connections after their
+// current response and shuts the server down gracefully once the delay (in
+// seconds) has passed. The drain endpoint is not authenticated, expose it on
+// internal networks only.
+var (
+	draining   int32
+	drainTimer *time.Timer
+	ready      int32
+
+	// The requests being handled by handleInternal
+	inFlightRequests int64
+)
+
+func init() {
+	OnAppStart(initDrain)
+}
+
+// initDrain registers the health and drain handlers if enabled.
+func initDrain() {
+	if Config.BoolDefault("server.health", false) {
+		AddHTTPMux(Config.StringDefault("server.health.path", "/_health"), http.HandlerFunc(healthHandler))
+	}
+	if Config.BoolDefault("server.ready", false) {
+		AddHTTPMux(Config.StringDefault("server.ready.path", "/_ready"), http.HandlerFunc(readyHandler))
+	}
+	if Config.BoolDefault("server.drain", false) {
+		AddHTTPMux(Config.StringDefault("server.drain.path", "/_drain"), http.HandlerFunc(drainHandler))
+	}
+}
+
+// Drain starts draining the server: the health check reports unhealthy,
+// keep-alive connections are closed and the server is shut down gracefully
+// after the drain delay. Calling it again has no effect.
+func Drain() {
+	if !atomic.CompareAndSwapInt32(&draining, 0, 1) {
+		return
+	}
+	if Server != nil {
+		Server.SetKeepAlivesEnabled(false)
+	}
+	delay := time.Duration(Config.IntDefault("server.drain.delay", 10)) * time.Second
+	INFO.Printf("Draining, shutting down in %s", delay)
+	drainTimer = time.AfterFunc(delay, func() {
+		if err := Stop(); err != nil {
+			ERROR.Println("Failed to stop:", err)
+		}
+	})
+}
+
+// SetReady sets whether the app is ready for requests, as reported by the
+// readiness check.
+func SetReady(isReady bool) {
+	var value int32
+	if isReady {
+		value = 1
+	}
+	atomic.StoreInt32(&ready, value)
+}
+
+// IsReady returns true if the app has been set ready and is not draining.
+func IsReady() bool {
+	return atomic.LoadInt32(&ready) == 1 && !IsDraining()
+}
+
+// IsDraining returns true while the server is draining or shutting down, and
+// once it has stopped, so that long running actions and background goroutines
+// can wrap up early.
+func IsDraining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
+
+// InFlightRequests returns the number of requests currently being handled,
+// including open websockets.
+func InFlightRequests() int {
+	return int(atomic.LoadInt64(&inFlightRequests))
+}
+
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	status, body := http.StatusOK, "ok"
+	if IsDraining() {
+		status, body = http.StatusServiceUnavailable, "draining"
+	}
+	writeCheck(w, status, body)
+}
+
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	status, body := http.StatusOK, "ready"
+	if IsDraining() {
+		status, body = http.StatusServiceUnavailable, "draining"
+	} else if !IsReady() {
+		status, body = http.StatusServiceUnavailable, "not ready"
+	}
+	writeCheck(w, status, body)
+}
+
+// writeCheck writes the response of a health or readiness check.
+func writeCheck(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(body)); err != nil {
+		ERROR.Println("Response write failed:", err)
+	}
+}
+
+func drainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	Drain()
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("server.health", "true")
+	Config.SetOption("server.drain", "true")
+	Config.SetOption("server.drain.delay", "60")
+	initDrain()
+	defer func() {
+		drainTimer.Stop()
+		atomic.StoreInt32(&draining, 0)
+	}()
+
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/_health", nil))
+	eq(t, "healthy status", http.StatusOK, resp.Code)
+
+	resp = httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/_drain", nil))
+	eq(t, "drain method", http.StatusMethodNotAllowed, resp.Code)
+
+	resp = httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("POST", "/_drain", nil))
+	eq(t, "drain status", http.StatusAccepted, resp.Code)
+
+	resp = httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/_health", nil))
+	eq(t, "draining status", http.StatusServiceUnavailable, resp.Code)
+	eq(t, "draining body", "draining", resp.Body.String())
+
+	resp = httptest.NewRecorder()
+	handle(resp, showRequest)
+	eq(t, "action status", http.StatusOK, resp.Code)
+	eq(t, "connection header", "close", resp.Header().Get("Connection"))
+}
+
+func TestReady(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("server.health", "true")
+	Config.SetOption("server.ready", "true")
+	hooks := startupHooks
+	defer func() {
+		startupHooks = hooks
+		SetReady(false)
+	}()
+	initDrain()
+	check := func(path string) int {
+		resp := httptest.NewRecorder()
+		handle(resp, httptest.NewRequest("GET", path, nil))
+		return resp.Code
+	}
+
+	eq(t, "live before the startup hooks", check("/_health"), http.StatusOK)
+	eq(t, "ready before the startup hooks", check("/_ready"), http.StatusServiceUnavailable)
+
+	// A startup hook marks the app ready once its dependencies are up
+	connected := false
+	OnAppStart(func() {
+		connected = true
+		SetReady(true)
+	})
+	InitServer()
+	eq(t, "connected", connected, true)
+	eq(t, "ready after the startup hooks", check("/_ready"), http.StatusOK)
+	eq(t, "live after the startup hooks", check("/_health"), http.StatusOK)
+
+	SetReady(false)
+	eq(t, "not ready again", check("/_ready"), http.StatusServiceUnavailable)
+}
+
+func TestInFlightRequests(t *testing.T) {
+	startFakeBookingApp()
+	filters := Filters
+	defer func() { Filters = filters }()
+	entered, release := make(chan struct{}), make(chan struct{})
+	Filters = []Filter{func(c *Controller, fc []Filter) {
+		close(entered)
+		<-release
+	}}
+
+	eq(t, "idle", 0, InFlightRequests())
+	done := make(chan struct{})
+	go func() {
+		handle(httptest.NewRecorder(), showRequest)
+		close(done)
+	}()
+	<-entered
+	eq(t, "blocked", 1, InFlightRequests())
+	close(release)
+	<-done
+	eq(t, "completed", 0, InFlightRequests())
+}
+
+func TestIsDrainingDuringShutdown(t *testing.T) {
+	startFakeBookingApp()
+	filters := Filters
+	defer func() { Filters = filters }()
+	entered, sawDraining := make(chan struct{}), make(chan bool, 1)
+	Filters = []Filter{func(c *Controller, fc []Filter) {
+		close(entered)
+		// Checkpoint once the shutdown has begun
+		deadline := time.Now().Add(5 * time.Second)
+		for !IsDraining() && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		sawDraining <- IsDraining()
+		c.Result = c.RenderText("checkpointed")
+	}}
+
+	addr, stop, err := RunTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "draining while running", IsDraining(), false)
+	go func() {
+		if resp, err := http.Get("http://" + addr + "/hotels"); err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	<-entered
+	stop()
+	eq(t, "draining seen by the action", <-sawDraining, true)
+	eq(t, "draining after shutdown", IsDraining(), true)
+
+	// Draining the stopped server has no effect
+	timer := drainTimer
+	Drain()
+	eq(t, "no drain timer", drainTimer == timer, true)
+
+	_, stop, err = RunTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	eq(t, "draining once restarted", IsDraining(), false)
+}
@@ -23,6 +23,13 @@ type Error struct {
 	Link                     string   // A configurable link to wrap the error source in
 }
 
+// discloseErrors reports whether error responses include the stack trace and
+// source context. It is set by errors.disclose and defaults to DevMode, so that
+// production deployments only show a generic message.
+func discloseErrors() bool {
+	return Config.BoolDefault("errors.disclose", DevMode)
+}
+
 // SourceLine structure to hold the per-source-line details.
 type SourceLine struct {
 	Source  string
@@ -61,8 +68,29 @@ func NewErrorFromPanic(err interface{}) *Error {
 		Line:        line,
 		Description: description,
 		SourceLines: MustReadLines(filename),
-		Stack:       stack,
+		Stack:       limitStack(stack),
+	}
+}
+
+// limitStack cuts the stack trace down to the first errors.stack.depth frames
+// (all of them by default) for display.
+func limitStack(stack string) string {
+	depth := Config.IntDefault("errors.stack.depth", 0)
+	if depth <= 0 {
+		return stack
+	}
+	lines := strings.SplitAfter(stack, "\n")
+	frames := 0
+	for i, line := range lines {
+		// Every frame ends with a tab indented file:line, apart from a trace
+		// starting at the file:line of a frame
+		if strings.HasPrefix(line, "\t") || i == 0 && !strings.HasPrefix(line, "goroutine ") {
+			if frames++; frames == depth {
+				return strings.Join(lines[:i+1], "")
+			}
+		}
 	}
+	return stack
 }
 
 // Error method constructs a plaintext version of the error, taking
@@ -89,16 +117,18 @@ func (e *Error) Error() string {
 }
 
 // ContextSource method returns a snippet of the source around
-// where the error occurred.
+// where the error occurred, errors.context.lines (5 by default) before and
+// after the line.
 func (e *Error) ContextSource() []SourceLine {
 	if e.SourceLines == nil {
 		return nil
 	}
-	start := (e.Line - 1) - 5
+	context := Config.IntDefault("errors.context.lines", 5)
+	start := (e.Line - 1) - context
 	if start < 0 {
 		start = 0
 	}
-	end := (e.Line - 1) + 5
+	end := e.Line + context
 	if end > len(e.SourceLines) {
 		end = len(e.SourceLines)
 	}
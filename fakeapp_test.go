@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync/atomic"
 )
 
 type Hotel struct {
@@ -39,7 +40,7 @@ type Application struct {
 func (c Hotels) Show(id int) Result {
 	title := "View Hotel"
 	hotel := &Hotel{id, "A Hotel", "300 Main St.", "New York", "NY", "10010", "USA", 300}
-	// The line number below must match the one with the code : RenderArgNames: map[int][]string{43: {"title", "hotel"}},
+	// The line number below must match the one with the code : RenderArgNames: map[int][]string{44: {"title", "hotel"}},
 	return c.Render(title, hotel)
 }
 
@@ -84,7 +85,7 @@ func registerControllers() {
 				Args: []*MethodArg{
 					{"id", reflect.TypeOf((*int)(nil))},
 				},
-				RenderArgNames: map[int][]string{43: {"title", "hotel"}},
+				RenderArgNames: map[int][]string{44: {"title", "hotel"}},
 			},
 			{
 				Name: "Book",
@@ -92,6 +93,12 @@ func registerControllers() {
 					{"id", reflect.TypeOf((*int)(nil))},
 				},
 			},
+			{
+				Name: "Stream",
+				Args: []*MethodArg{
+					{"ws", reflect.PtrTo(websocketType)},
+				},
+			},
 		})
 
 	RegisterController((*Static)(nil),
@@ -152,5 +159,8 @@ func startFakeBookingApp() {
 
 	registerControllers()
 
+	// Not draining, as after a server stopped by an earlier test
+	atomic.StoreInt32(&draining, 0)
+
 	runStartupHooks()
 }
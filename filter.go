@@ -13,6 +13,8 @@ var Filters = []Filter{
 	PanicFilter,             // Recover from panics and display an error page instead.
 	RouterFilter,            // Use the routing table to select the right Action.
 	FilterConfiguringFilter, // A hook for adding or removing per-Action filters.
+	ConsumesFilter,          // Refuse request bodies of content types the action doesn't accept.
+	DecompressRequestFilter, // Decompress gzip and deflate request bodies.
 	ParamsFilter,            // Parse parameters into Controller.Params.
 	SessionFilter,           // Restore and write the session cookie.
 	FlashFilter,             // Restore and write the flash cookie.
@@ -205,6 +205,7 @@ func FilterEq(a, b Filter) bool {
 // filter chain for the action being invoked.
 func FilterConfiguringFilter(c *Controller, fc []Filter) {
 	if newChain := getOverrideChain(c.Name, c.Action); newChain != nil {
+		newChain = traceOverrideChain(c, fc, newChain)
 		newChain[0](c, newChain[1:])
 		return
 	}
@@ -6,11 +6,14 @@ package revel
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"golang.org/x/net/websocket"
 	"path/filepath"
@@ -24,6 +27,9 @@ type Request struct {
 	AcceptLanguages AcceptLanguages
 	Locale          string
 	Websocket       *websocket.Conn
+	RoutePath       string // The pattern of the matched route, e.g. /hotels/:id
+
+	body *countingReader // The request body, counting the bytes read
 }
 
 // Response Revel's HTTP response object structure
@@ -32,6 +38,12 @@ type Response struct {
 	ContentType string
 
 	Out http.ResponseWriter
+
+	capture *captureResponseWriter // The body buffer with response.capture
+
+	// The writer of the server, bypassing the wrappers of Out which take the
+	// first status written for the final one, to send informational responses
+	informational http.ResponseWriter
 }
 
 // NewResponse returns a Revel's HTTP response instance with given instance
@@ -41,12 +53,90 @@ func NewResponse(w http.ResponseWriter) *Response {
 
 // NewRequest returns a Revel's HTTP request instance with given HTTP instance
 func NewRequest(r *http.Request) *Request {
-	return &Request{
+	req := &Request{
 		Request:         r,
 		ContentType:     ResolveContentType(r),
 		Format:          ResolveFormat(r),
 		AcceptLanguages: ResolveAcceptLanguage(r),
 	}
+	if r.Body != nil {
+		req.body = &countingReader{ReadCloser: r.Body}
+		r.Body = req.body
+	}
+	return req
+}
+
+// BytesRead returns the number of bytes of the request body read so far, for
+// reporting the progress of an upload. It is safe to call while the body is
+// being read.
+func (req *Request) BytesRead() int64 {
+	if req.body == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&req.body.n)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(&r.n, int64(n))
+	return n, err
+}
+
+// IfMatch reports whether the If-Match header permits the request to modify
+// the resource with the current etag, for optimistic concurrency control.
+// That is the case if the header is absent, is "*" or lists the etag, which
+// is compared strongly. Respond with c.PreconditionFailed otherwise.
+func (req *Request) IfMatch(etag string) bool {
+	header := req.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	etag = quoteETag(etag)
+	for _, tag := range parseETags(header) {
+		if tag == "*" || (tag == etag && !strings.HasPrefix(tag, "W/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// IfNoneMatch reports whether the If-None-Match header is "*" or lists the
+// current etag of the resource, which is compared weakly. A GET may then be
+// answered with 304 Not Modified, while a PUT should fail with a 412.
+func (req *Request) IfNoneMatch(etag string) bool {
+	etag = strings.TrimPrefix(quoteETag(etag), "W/")
+	for _, tag := range parseETags(req.Header.Get("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseETags returns the etags of a comma separated If-Match or
+// If-None-Match header.
+func parseETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// quoteETag quotes an etag given without the quotes of the header syntax.
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
 }
 
 // WriteHeader writes the header (for now, just the status code).
@@ -60,9 +150,147 @@ func (resp *Response) WriteHeader(defaultStatusCode int, defaultContentType stri
 		resp.ContentType = defaultContentType
 	}
 	resp.Out.Header().Set("Content-Type", resp.ContentType)
+	// Trailers can only be sent with a chunked response
+	if resp.hasTrailers() {
+		resp.Out.Header().Del("Content-Length")
+	}
 	resp.Out.WriteHeader(resp.Status)
 }
 
+// Flush sends the data written so far to the client, if the underlying
+// writer supports it (otherwise it does nothing). Use it for long-polling
+// and progress responses written to resp.Out. Note that rendered templates
+// are buffered before being written unless results.chunked is enabled or
+// they are rendered with RenderStream.
+func (resp *Response) Flush() {
+	if flusher, ok := resp.Out.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// SetTrailer sets an HTTP trailer, which is sent to the client after the
+// response body. It may be called before or after the body has been written,
+// as long as the action has not returned yet.
+func (resp *Response) SetTrailer(name, value string) {
+	resp.Out.Header()[http.TrailerPrefix+http.CanonicalHeaderKey(name)] = []string{value}
+}
+
+// AppendVary adds the request header field to the Vary header of the response,
+// for caches to tell apart the responses to requests differing in the field.
+// Fields already listed are not repeated.
+func (resp *Response) AppendVary(field string) {
+	appendVary(resp.Out.Header(), field)
+}
+
+func appendVary(header http.Header, field string) {
+	var fields []string
+	for _, value := range header["Vary"] {
+		for _, existing := range strings.Split(value, ",") {
+			if existing = strings.TrimSpace(existing); existing == "*" || strings.EqualFold(existing, field) {
+				return
+			} else if existing != "" {
+				fields = append(fields, existing)
+			}
+		}
+	}
+	header.Set("Vary", strings.Join(append(fields, http.CanonicalHeaderKey(field)), ", "))
+}
+
+// hasTrailers returns true if a trailer has been set on the response.
+func (resp *Response) hasTrailers() bool {
+	for key := range resp.Out.Header() {
+		if strings.HasPrefix(key, http.TrailerPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// headResponseWriter discards the body of the response to a HEAD request.
+// The header is held back until the response is closed, so that it carries
+// the Content-Length of the body a GET request would have received.
+type headResponseWriter struct {
+	http.ResponseWriter
+	status int
+	length int
+}
+
+func (w *headResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.length += len(b)
+	return len(b), nil
+}
+
+func (w *headResponseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Close writes the held back header.
+func (w *headResponseWriter) Close() error {
+	if w.status == 0 {
+		return nil
+	}
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Length") == "" && header.Get("Transfer-Encoding") == "" &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		header.Set("Content-Length", strconv.Itoa(w.length))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	return nil
+}
+
+// ErrResponseTooLarge is returned by writes to a response exceeding the
+// configured response.maxsize.
+var ErrResponseTooLarge = errors.New("revel: response exceeds response.maxsize")
+
+// maxSizeResponseWriter stops writing once the body of the response reaches
+// the maximum size, see response.maxsize.
+type maxSizeResponseWriter struct {
+	http.ResponseWriter
+	remaining int64
+	exceeded  bool
+}
+
+func (w *maxSizeResponseWriter) Write(b []byte) (int, error) {
+	if w.exceeded {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(b)) <= w.remaining {
+		w.remaining -= int64(len(b))
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.exceeded = true
+	n, err := w.ResponseWriter.Write(b[:w.remaining])
+	w.remaining = 0
+	if err == nil {
+		err = ErrResponseTooLarge
+	}
+	return n, err
+}
+
+func (w *maxSizeResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *maxSizeResponseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
 // ResolveContentType gets the content type.
 // e.g. From "multipart/form-data; boundary=--" to "multipart/form-data"
 // If none is specified, returns "text/html" by default.
@@ -79,16 +307,24 @@ func ResolveContentType(req *http.Request) string {
 // returning a default of "html" when Accept header cannot be mapped to a
 // value above.
 func ResolveFormat(req *http.Request) string {
+	format, _ := negotiateFormat(req)
+	return format
+}
+
+// negotiateFormat returns the format of the request like ResolveFormat, and
+// whether it was requested rather than defaulted to for an Accept header
+// without any of the known types.
+func negotiateFormat(req *http.Request) (string, bool) {
 	ext := strings.ToLower(filepath.Ext(req.URL.Path))
 	switch ext {
 	case ".html":
-		return "html"
+		return "html", true
 	case ".json":
-		return "json"
+		return "json", true
 	case ".xml":
-		return "xml"
+		return "xml", true
 	case ".txt":
-		return "txt"
+		return "txt", true
 	}
 
 	accept := req.Header.Get("accept")
@@ -98,19 +334,19 @@ func ResolveFormat(req *http.Request) string {
 		strings.HasPrefix(accept, "*/*"), // */
 		strings.Contains(accept, "application/xhtml"),
 		strings.Contains(accept, "text/html"):
-		return "html"
+		return "html", true
 	case strings.Contains(accept, "application/json"),
 		strings.Contains(accept, "text/javascript"),
 		strings.Contains(accept, "application/javascript"):
-		return "json"
+		return "json", true
 	case strings.Contains(accept, "application/xml"),
 		strings.Contains(accept, "text/xml"):
-		return "xml"
+		return "xml", true
 	case strings.Contains(accept, "text/plain"):
-		return "txt"
+		return "txt", true
 	}
 
-	return "html"
+	return "html", false
 }
 
 // AcceptLanguage is a single language from the Accept-Language HTTP header.
@@ -208,7 +208,7 @@ func loadTestI18nConfig(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Unable to load test config '%s': %s", testConfigName, err.Error())
 	}
-	Config = testConfig
+	Config = NewConfigContext(testConfig)
 	CookiePrefix = Config.StringDefault("cookie.prefix", "REVEL")
 }
 
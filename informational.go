@@ -0,0 +1,18 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build go1.19
+// +build go1.19
+
+package revel
+
+import "net/http"
+
+// Since Go 1.19 a 1xx status written by a handler is sent as an
+// informational response, rather than taken for the final status.
+var informationalSupported = true
+
+func writeEarlyHints(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusEarlyHints)
+}
@@ -0,0 +1,16 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.19
+// +build !go1.19
+
+package revel
+
+import "net/http"
+
+// Before Go 1.19 a 1xx status written by a handler is taken for the final
+// status of the response, so informational responses are not sent.
+var informationalSupported = false
+
+func writeEarlyHints(w http.ResponseWriter) {}
@@ -6,7 +6,10 @@ package revel
 
 import (
 	"io"
+	"net/http"
 	"reflect"
+	"strings"
+	"sync"
 
 	"golang.org/x/net/websocket"
 )
@@ -17,19 +20,103 @@ var (
 	websocketType     = reflect.TypeOf((*websocket.Conn)(nil))
 )
 
+// The binding plans of the actions, made the first time an action is invoked
+// rather than on every request, and dropped when the routes are reloaded.
+var (
+	actionPlans     = map[*MethodType]*actionPlan{}
+	actionPlansLock sync.RWMutex
+)
+
+// actionPlan is what the ActionInvoker needs to know about an action method
+// to call it.
+type actionPlan struct {
+	method int // The index of the method in the controller type, or -1
+	args   []argPlan
+}
+
+type argPlan struct {
+	name      string
+	typ       reflect.Type
+	binder    Binder
+	bindable  bool
+	websocket bool
+	required  bool
+}
+
+func init() {
+	OnRoutesLoaded(func(*Router) { resetActionPlans() })
+}
+
+// resetActionPlans drops the binding plans of the actions.
+func resetActionPlans() {
+	actionPlansLock.Lock()
+	defer actionPlansLock.Unlock()
+	actionPlans = map[*MethodType]*actionPlan{}
+}
+
+// planAction returns the binding plan of the action of the controller.
+func planAction(c *Controller) *actionPlan {
+	actionPlansLock.RLock()
+	plan, found := actionPlans[c.MethodType]
+	actionPlansLock.RUnlock()
+	if found {
+		return plan
+	}
+
+	plan = &actionPlan{method: -1, args: make([]argPlan, len(c.MethodType.Args))}
+	if method, found := reflect.TypeOf(c.AppController).MethodByName(c.MethodType.Name); found {
+		plan.method = method.Index
+	}
+	for i, arg := range c.MethodType.Args {
+		binder, bindable := binderForType(arg.Type)
+		plan.args[i] = argPlan{
+			name:      arg.Name,
+			typ:       arg.Type,
+			binder:    binder,
+			bindable:  bindable,
+			websocket: arg.Type == websocketType,
+			required:  paramRequired(arg.Type),
+		}
+	}
+
+	actionPlansLock.Lock()
+	defer actionPlansLock.Unlock()
+	actionPlans[c.MethodType] = plan
+	return plan
+}
+
 func ActionInvoker(c *Controller, _ []Filter) {
 	// Instantiate the method.
-	methodValue := reflect.ValueOf(c.AppController).MethodByName(c.MethodType.Name)
+	plan := planAction(c)
+	var methodValue reflect.Value
+	if plan.method >= 0 {
+		methodValue = reflect.ValueOf(c.AppController).Method(plan.method)
+	} else {
+		methodValue = reflect.ValueOf(c.AppController).MethodByName(c.MethodType.Name)
+	}
+
+	// In strict mode, missing required arguments are validation errors.
+	strict := Config.BoolDefault("binding.strict", false)
+	if strict && c.Validation == nil {
+		c.Validation = &Validation{}
+	}
 
 	// Collect the values for the method's arguments.
-	var methodArgs []reflect.Value
-	for _, arg := range c.MethodType.Args {
+	methodArgs := make([]reflect.Value, 0, len(plan.args))
+	for _, arg := range plan.args {
 		// If they accept a websocket connection, treat that arg specially.
 		var boundArg reflect.Value
-		if arg.Type == websocketType {
+		if arg.websocket {
 			boundArg = reflect.ValueOf(c.Request.Websocket)
 		} else {
-			boundArg = Bind(c.Params, arg.Name, arg.Type)
+			if arg.bindable {
+				boundArg = arg.binder.Bind(c.Params, arg.name, arg.typ)
+			} else {
+				boundArg = reflect.Zero(arg.typ)
+			}
+			if strict && arg.required && !paramPresent(c.Params, arg.name) {
+				c.Validation.Error("Required parameter %s is missing", arg.name).Key(arg.name)
+			}
 			// #756 - If the argument is a closer, defer a Close call,
 			// so we don't risk on leaks.
 			if closer, ok := boundArg.Interface().(io.Closer); ok {
@@ -41,6 +128,22 @@ func ActionInvoker(c *Controller, _ []Filter) {
 		methodArgs = append(methodArgs, boundArg)
 	}
 
+	// Params which failed to convert are validation errors.
+	if len(c.Params.bindErrors) > 0 {
+		if c.Validation == nil {
+			c.Validation = &Validation{}
+		}
+		for _, err := range c.Params.bindErrors {
+			c.Validation.Error(err.Error()).Key(err.Field).Error.Bind = err
+		}
+	}
+
+	// In strict JSON mode a body which doesn't fit the arguments is refused.
+	if err := c.Params.jsonErr; err != nil {
+		c.Result = c.Abort(http.StatusBadRequest, "Invalid JSON request body: "+err.Error())
+		return
+	}
+
 	var resultValue reflect.Value
 	if methodValue.Type().IsVariadic() {
 		resultValue = methodValue.CallSlice(methodArgs)[0]
@@ -50,4 +153,38 @@ func ActionInvoker(c *Controller, _ []Filter) {
 	if resultValue.Kind() == reflect.Interface && !resultValue.IsNil() {
 		c.Result = resultValue.Interface().(Result)
 	}
+
+	// Render the body right away for the filters to inspect with
+	// response.capture, see CapturedBody
+	if c.Response.capture != nil && c.Result != nil {
+		c.Result.Apply(c.Request, c.Response)
+		c.Result = nil
+	}
+}
+
+// paramRequired returns true if an argument of the type must be provided in
+// strict binding mode. Pointer arguments are optional.
+func paramRequired(typ reflect.Type) bool {
+	return typ.Kind() != reflect.Ptr
+}
+
+// paramPresent returns true if the request provides a value for the param,
+// directly or for any of its fields or elements.
+func paramPresent(params *Params, name string) bool {
+	if _, found := params.Values[name]; found {
+		return true
+	}
+	if _, found := params.Files[name]; found {
+		return true
+	}
+	// A JSON body may be bound to any argument
+	if len(params.JSON) > 0 {
+		return true
+	}
+	for key := range params.Values {
+		if strings.HasPrefix(key, name+".") || strings.HasPrefix(key, name+"[") {
+			return true
+		}
+	}
+	return false
 }
@@ -5,8 +5,12 @@
 package revel
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -42,6 +46,100 @@ func TestFindControllers(t *testing.T) {
 	checkSearchResults(t, PP2{}, [][]int{{0}, {1, 0}, {2, 0}, {3, 0, 0, 0}})
 }
 
+func TestStrictBinding(t *testing.T) {
+	startFakeBookingApp()
+	invoke := func() *Controller {
+		c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+		if err := c.SetAction("Hotels", "Show"); err != nil {
+			t.Fatalf("SetAction failed: %s", err)
+		}
+		c.Params.Values = url.Values{"ID": {"3"}} // A typo, the param is id
+		c.Validation = &Validation{}
+		ActionInvoker(c, nil)
+		return c
+	}
+
+	if c := invoke(); c.Validation.HasErrors() {
+		t.Errorf("Expected the missing param to be ignored, got %v", c.Validation.Errors)
+	}
+
+	Config.SetOption("binding.strict", "true")
+	c := invoke()
+	if !c.Validation.HasErrors() {
+		t.Fatal("Expected a validation error for the missing param")
+	}
+	eq(t, "error key", "id", c.Validation.Errors[0].Key)
+
+	if paramRequired(reflect.TypeOf((*int)(nil))) {
+		t.Error("Expected pointer params to be optional")
+	}
+}
+
+// Test that a param which fails to convert is a validation error carrying
+// the BindError.
+func TestBindError(t *testing.T) {
+	startFakeBookingApp()
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	if err := c.SetAction("Hotels", "Show"); err != nil {
+		t.Fatalf("SetAction failed: %s", err)
+	}
+	c.Params.Values = url.Values{"id": {"3x"}}
+	ActionInvoker(c, nil)
+
+	if c.Validation == nil || len(c.Validation.Errors) != 1 {
+		t.Fatalf("Expected a validation error, got %v", c.Validation)
+	}
+	verr := c.Validation.Errors[0]
+	eq(t, "error key", verr.Key, "id")
+	bindErr := verr.Bind
+	if bindErr == nil {
+		t.Fatal("Expected the validation error to carry the BindError")
+	}
+	eq(t, "field", bindErr.Field, "id")
+	eq(t, "value", bindErr.Value, "3x")
+	eq(t, "type", bindErr.Type, reflect.TypeOf(0))
+	if _, ok := bindErr.Err.(*strconv.NumError); !ok {
+		t.Errorf("Expected the conversion error, got %#v", bindErr.Err)
+	}
+	eq(t, "message", verr.Message, bindErr.Error())
+}
+
+type Bookings struct{ *Controller }
+
+func (c Bookings) Create(hotel Hotel) Result {
+	return c.RenderText(hotel.Name)
+}
+
+func TestStrictJSON(t *testing.T) {
+	startFakeBookingApp()
+	RegisterController((*Bookings)(nil), []*MethodType{{
+		Name: "Create",
+		Args: []*MethodArg{{Name: "hotel", Type: reflect.TypeOf((*Hotel)(nil))}},
+	}})
+	invoke := func() *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(httptest.NewRequest("POST", "/bookings/create", nil)), NewResponse(resp))
+		if err := c.SetAction("Bookings", "Create"); err != nil {
+			t.Fatalf("SetAction failed: %s", err)
+		}
+		c.Params.JSON = []byte(`{"Name": "A Hotel", "Rating": 5}`)
+		ActionInvoker(c, nil)
+		c.Result.Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := invoke()
+	eq(t, "lenient status", resp.Code, http.StatusOK)
+	eq(t, "lenient binding", resp.Body.String(), "A Hotel")
+
+	Config.SetOption("format.json.strict", "true")
+	resp = invoke()
+	eq(t, "strict status", resp.Code, http.StatusBadRequest)
+	if !strings.Contains(resp.Body.String(), "Rating") {
+		t.Errorf("Expected the unknown field in the response, got %q", resp.Body.String())
+	}
+}
+
 func checkSearchResults(t *testing.T, obj interface{}, expected [][]int) {
 	actual := findControllers(reflect.TypeOf(obj))
 	if !reflect.DeepEqual(expected, actual) {
@@ -125,11 +223,64 @@ func BenchmarkInvoker(b *testing.B) {
 		return
 	}
 	c.Request = NewRequest(showRequest)
+	c.Response = NewResponse(httptest.NewRecorder())
+	c.Params = &Params{Values: make(url.Values)}
+	c.Params.Set("id", "3")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ActionInvoker(&c, nil)
+	}
+}
+
+func TestActionPlanReload(t *testing.T) {
+	startFakeBookingApp()
+	book := func(id string) int {
+		c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+		if err := c.SetAction("Hotels", "Book"); err != nil {
+			t.Fatalf("Failed to set action: %s", err)
+		}
+		c.Params = &Params{Values: url.Values{"id": {id}}}
+		ActionInvoker(c, nil)
+		return c.Result.(RenderJSONResult).obj.(*Hotel).HotelID
+	}
+
+	eq(t, "first invocation", book("3"), 3)
+	eq(t, "cached invocation", book("4"), 4)
+	actionPlansLock.RLock()
+	eq(t, "cached plans", len(actionPlans), 1)
+	actionPlansLock.RUnlock()
+
+	if err := MainRouter.Refresh(); err != nil {
+		t.Fatalf("Failed to reload routes: %s", err)
+	}
+	actionPlansLock.RLock()
+	eq(t, "plans after reload", len(actionPlans), 0)
+	actionPlansLock.RUnlock()
+	eq(t, "invocation after reload", book("5"), 5)
+}
+
+// BenchmarkInvokerUnplanned invokes the action like BenchmarkInvoker, but
+// without the binding plan of the action cached.
+func BenchmarkInvokerUnplanned(b *testing.B) {
+	startFakeBookingApp()
+	c := Controller{
+		ViewArgs: make(map[string]interface{}),
+	}
+	if err := c.SetAction("Hotels", "Show"); err != nil {
+		b.Errorf("Failed to set action: %s", err)
+		return
+	}
+	c.Request = NewRequest(showRequest)
+	c.Response = NewResponse(httptest.NewRecorder())
 	c.Params = &Params{Values: make(url.Values)}
 	c.Params.Set("id", "3")
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
+		resetActionPlans()
 		ActionInvoker(&c, nil)
 	}
 }
@@ -0,0 +1,216 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	// Register the hashes of the algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// JWTClaimsArg is the key of the claims of a validated token in the
+// Controller.Args, as a map[string]interface{}.
+const JWTClaimsArg = "claims"
+
+// The supported signing algorithms of tokens
+var jwtAlgorithms = map[string]struct {
+	hash crypto.Hash
+	rsa  bool
+}{
+	"HS256": {crypto.SHA256, false},
+	"HS384": {crypto.SHA384, false},
+	"HS512": {crypto.SHA512, false},
+	"RS256": {crypto.SHA256, true},
+	"RS384": {crypto.SHA384, true},
+	"RS512": {crypto.SHA512, true},
+}
+
+// The key verifying the signatures of tokens, a []byte secret for the HS
+// algorithms and an *rsa.PublicKey for the RS algorithms
+var (
+	jwtKey     interface{}
+	jwtKeyLock sync.RWMutex
+)
+
+func init() {
+	OnAppStart(loadJWTKey)
+	OnConfigReload(loadJWTKey)
+}
+
+// JWTFilter requires requests to carry a JSON Web Token in an
+// "Authorization: Bearer" header, responding with a 401 when the token is
+// missing, expired (exp), not yet valid (nbf) or not signed with the key.
+// The claims of a valid token are stored in c.Args[JWTClaimsArg].
+// It is configured in app.conf:
+//
+//	auth.jwt.algorithm = HS256              # or HS384, HS512, RS256, RS384, RS512
+//	auth.jwt.secret    = <secret>           # key of the HS algorithms
+//	auth.jwt.publickey = conf/jwt.pem       # PEM file of the RS algorithms
+//	auth.jwt.exempt    = /login, /public/   # path prefixes without a token
+//
+// Only tokens signed with the configured algorithm are accepted.
+func JWTFilter(c *Controller, fc []Filter) {
+	if hasPathPrefix(c.Request.URL.Path, configPrefixes("auth.jwt.exempt")) {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	auth := c.Request.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		c.Response.Out.Header().Set("WWW-Authenticate", "Bearer")
+		c.Result = c.Unauthorized("Bearer token required")
+		return
+	}
+
+	claims, err := verifyJWT(strings.TrimSpace(auth[7:]), time.Now())
+	if err != nil {
+		c.Response.Out.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.Result = c.Unauthorized("Invalid token: %s", err)
+		return
+	}
+	c.Args[JWTClaimsArg] = claims
+	fc[0](c, fc[1:])
+}
+
+// loadJWTKey loads the configured key of the JWTFilter.
+func loadJWTKey() {
+	var key interface{}
+	algorithm := Config.StringDefault("auth.jwt.algorithm", "HS256")
+	if alg, ok := jwtAlgorithms[algorithm]; !ok {
+		ERROR.Printf("JWT: unsupported algorithm %s", algorithm)
+	} else if !alg.rsa {
+		if secret := Config.StringDefault("auth.jwt.secret", ""); secret != "" {
+			key = []byte(secret)
+		}
+	} else if path := Config.StringDefault("auth.jwt.publickey", ""); path != "" {
+		var err error
+		if key, err = loadRSAPublicKey(path); err != nil {
+			ERROR.Printf("JWT: failed to load public key %s: %s", path, err)
+		}
+	}
+
+	jwtKeyLock.Lock()
+	jwtKey = key
+	jwtKeyLock.Unlock()
+}
+
+// loadRSAPublicKey reads a PEM encoded RSA public key, or the key of a
+// certificate, resolving a relative path against the application base path.
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(BasePath, path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var key interface{}
+	if block.Type == "CERTIFICATE" {
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	} else {
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, ok := key.(*rsa.PublicKey); ok {
+		return rsaKey, nil
+	}
+	return nil, errors.New("not an RSA public key")
+}
+
+// verifyJWT verifies the signature and validity period of the token at now,
+// returning its claims.
+func verifyJWT(token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	algorithm := Config.StringDefault("auth.jwt.algorithm", "HS256")
+	alg, ok := jwtAlgorithms[algorithm]
+	if !ok || header.Alg != algorithm {
+		return nil, fmt.Errorf("unexpected algorithm %s", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	jwtKeyLock.RLock()
+	key := jwtKey
+	jwtKeyLock.RUnlock()
+
+	signed := parts[0] + "." + parts[1]
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(alg.hash.New, key)
+		mac.Write([]byte(signed))
+		if alg.rsa || !hmac.Equal(mac.Sum(nil), signature) {
+			return nil, errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		hash := alg.hash.New()
+		hash.Write([]byte(signed))
+		if !alg.rsa || rsa.VerifyPKCS1v15(key, alg.hash, hash.Sum(nil), signature) != nil {
+			return nil, errors.New("invalid signature")
+		}
+	default:
+		return nil, errors.New("no key configured")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if exp, ok := claims["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return nil, errors.New("token not valid yet")
+	}
+	return claims, nil
+}
+
+// decodeJWTSegment unmarshals a base64url encoded JSON segment of a token.
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
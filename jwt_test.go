@@ -0,0 +1,129 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signJWT returns a token of the claims signed by sign.
+func signJWT(alg string, claims map[string]interface{}, sign func(signed string) []byte) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(signed))
+}
+
+func hs256(secret string) func(string) []byte {
+	return func(signed string) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(signed))
+		return mac.Sum(nil)
+	}
+}
+
+func filterJWT(path, token string) *Controller {
+	r := httptest.NewRequest("GET", path, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	c := NewController(NewRequest(r), NewResponse(httptest.NewRecorder()))
+	JWTFilter(c, NilChain)
+	return c
+}
+
+func TestJWTFilter(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("auth.jwt.secret", "secret")
+	Config.SetOption("auth.jwt.exempt", "/login")
+	loadJWTKey()
+
+	valid := signJWT("HS256", map[string]interface{}{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}, hs256("secret"))
+	c := filterJWT("/hotels", valid)
+	eq(t, "valid token result", c.Result, nil)
+	claims, _ := c.Args[JWTClaimsArg].(map[string]interface{})
+	eq(t, "valid token subject", claims["sub"], "alice")
+
+	expired := signJWT("HS256", map[string]interface{}{"sub": "alice", "exp": time.Now().Add(-time.Minute).Unix()}, hs256("secret"))
+	c = filterJWT("/hotels", expired)
+	eq(t, "expired token status", c.Response.Status, http.StatusUnauthorized)
+	eq(t, "expired token claims", c.Args[JWTClaimsArg], nil)
+
+	parts := strings.Split(valid, ".")
+	payload, _ := json.Marshal(map[string]interface{}{"sub": "admin", "exp": time.Now().Add(time.Hour).Unix()})
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	c = filterJWT("/hotels", tampered)
+	eq(t, "tampered token status", c.Response.Status, http.StatusUnauthorized)
+
+	c = filterJWT("/hotels", signJWT("HS256", map[string]interface{}{"sub": "alice"}, hs256("other")))
+	eq(t, "wrong key status", c.Response.Status, http.StatusUnauthorized)
+
+	c = filterJWT("/hotels", signJWT("none", map[string]interface{}{"sub": "alice"}, func(string) []byte { return nil }))
+	eq(t, "unsigned token status", c.Response.Status, http.StatusUnauthorized)
+
+	c = filterJWT("/hotels", "")
+	eq(t, "missing token status", c.Response.Status, http.StatusUnauthorized)
+	eq(t, "missing token challenge", c.Response.Out.Header().Get("WWW-Authenticate"), "Bearer")
+
+	c = filterJWT("/login", "")
+	eq(t, "exempt path result", c.Result, nil)
+}
+
+func TestJWTFilterRSA(t *testing.T) {
+	startFakeBookingApp()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "revel-jwt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwt.pem")
+	if err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	Config.SetOption("auth.jwt.algorithm", "RS256")
+	Config.SetOption("auth.jwt.publickey", path)
+	loadJWTKey()
+	defer func() {
+		Config.SetOption("auth.jwt.algorithm", "HS256")
+		loadJWTKey()
+	}()
+
+	rs256 := func(signed string) []byte {
+		digest := sha256.Sum256([]byte(signed))
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		return signature
+	}
+	c := filterJWT("/hotels", signJWT("RS256", map[string]interface{}{"sub": "alice"}, rs256))
+	eq(t, "valid token result", c.Result, nil)
+
+	// A public key used as HMAC secret must not be accepted
+	c = filterJWT("/hotels", signJWT("HS256", map[string]interface{}{"sub": "alice"}, hs256(string(der))))
+	eq(t, "algorithm confusion status", c.Response.Status, http.StatusUnauthorized)
+}
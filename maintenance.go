@@ -0,0 +1,85 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Maintenance mode answers every request with 503 Service Unavailable, before
+// the filters, except those from the allowed clients. It is toggled with
+// SetMaintenance and configured in app.conf:
+//
+//	maintenance.allow = 10.0.0.0/8, 192.168.1.20
+//	maintenance.page  = public/maintenance.html
+//	maintenance.retryafter = 300
+//
+// The allowed clients are IP addresses or CIDR ranges, matched against the
+// ClientIP. The page is an HTML file, relative to the BasePath.
+var maintenance int32
+
+// SetMaintenance turns maintenance mode on or off.
+func SetMaintenance(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&maintenance, value)
+}
+
+// InMaintenance returns true when maintenance mode is on.
+func InMaintenance() bool {
+	return atomic.LoadInt32(&maintenance) == 1
+}
+
+// maintenanceAllowed returns true if the client may use the app during
+// maintenance.
+func maintenanceAllowed(clientIP string) bool {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, allowed := range strings.Split(Config.StringDefault("maintenance.allow", ""), ",") {
+		allowed = strings.TrimSpace(allowed)
+		if strings.Contains(allowed, "/") {
+			if _, network, err := net.ParseCIDR(allowed); err == nil && network.Contains(ip) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(allowed); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeMaintenance answers a request during maintenance.
+func writeMaintenance(w http.ResponseWriter) {
+	if retryAfter := Config.StringDefault("maintenance.retryafter", ""); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if page := Config.StringDefault("maintenance.page", ""); page != "" {
+		if !filepath.IsAbs(page) {
+			page = filepath.Join(BasePath, page)
+		}
+		content, err := ioutil.ReadFile(page)
+		if err == nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			if _, err = w.Write(content); err != nil {
+				ERROR.Println("Response write failed:", err)
+			}
+			return
+		}
+		WARN.Println("Failed to read maintenance.page:", err)
+	}
+	http.Error(w, "Down for maintenance", http.StatusServiceUnavailable)
+}
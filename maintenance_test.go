@@ -0,0 +1,50 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestMaintenance(t *testing.T) {
+	startFakeBookingApp()
+	page, err := ioutil.TempFile("", "maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(page.Name())
+	_, _ = page.WriteString("<h1>Back soon</h1>")
+	_ = page.Close()
+	Config.SetOption("maintenance.page", page.Name())
+	Config.SetOption("maintenance.allow", "10.1.0.0/16, 198.51.100.7")
+	Config.SetOption("maintenance.retryafter", "120")
+	defer SetMaintenance(false)
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/hotels", nil)
+		r.RemoteAddr = remoteAddr
+		resp := httptest.NewRecorder()
+		handle(resp, r)
+		return resp
+	}
+
+	eq(t, "before maintenance", request("192.0.2.1:1234").Code, http.StatusOK)
+
+	SetMaintenance(true)
+	resp := request("192.0.2.1:1234")
+	eq(t, "blocked status", resp.Code, http.StatusServiceUnavailable)
+	eq(t, "blocked page", resp.Body.String(), "<h1>Back soon</h1>")
+	eq(t, "retry after", resp.Header().Get("Retry-After"), "120")
+	eq(t, "allowed address", request("198.51.100.7:1234").Code, http.StatusOK)
+	eq(t, "allowed range", request("10.1.2.3:1234").Code, http.StatusOK)
+	eq(t, "outside the range", request("10.2.0.1:1234").Code, http.StatusServiceUnavailable)
+
+	SetMaintenance(false)
+	eq(t, "after maintenance", request("192.0.2.1:1234").Code, http.StatusOK)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Request metrics, collected by handleInternal when enabled in app.conf:
+//
+//	server.metrics      = true
+//	server.metrics.path = /_metrics
+//
+// They are served in the Prometheus text format.
+var (
+	metricsEnabled bool
+
+	// Upper bounds of the latency histogram buckets, in seconds
+	metricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	metrics = newRequestMetrics()
+)
+
+type requestMetrics struct {
+	statusClasses [6]uint64 // Requests by status class, index 0 is unknown
+	buckets       []uint64  // Requests by latency bucket, not cumulative
+	count         uint64
+	durationSum   uint64 // Nanoseconds
+}
+
+func init() {
+	OnAppStart(initMetrics)
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{buckets: make([]uint64, len(metricsBuckets))}
+}
+
+// initMetrics registers the metrics handler if enabled.
+func initMetrics() {
+	metricsEnabled = Config.BoolDefault("server.metrics", false)
+	if metricsEnabled {
+		AddHTTPMux(Config.StringDefault("server.metrics.path", "/_metrics"), http.HandlerFunc(metricsHandler))
+	}
+}
+
+// observe records a served request.
+func (m *requestMetrics) observe(status int, duration time.Duration) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	class := status / 100
+	if class < 1 || class > 5 {
+		class = 0
+	}
+	atomic.AddUint64(&m.statusClasses[class], 1)
+
+	seconds := duration.Seconds()
+	for i, bound := range metricsBuckets {
+		if seconds <= bound {
+			atomic.AddUint64(&m.buckets[i], 1)
+			break
+		}
+	}
+	atomic.AddUint64(&m.count, 1)
+	atomic.AddUint64(&m.durationSum, uint64(duration))
+}
+
+// write writes the metrics in the Prometheus text format.
+func (m *requestMetrics) write(b *bytes.Buffer) {
+	b.WriteString("# HELP revel_requests_total Number of requests served, by status class.\n")
+	b.WriteString("# TYPE revel_requests_total counter\n")
+	for class := range m.statusClasses {
+		label := "unknown"
+		if class > 0 {
+			label = strconv.Itoa(class) + "xx"
+		}
+		fmt.Fprintf(b, "revel_requests_total{class=%q} %d\n", label, atomic.LoadUint64(&m.statusClasses[class]))
+	}
+
+	b.WriteString("# HELP revel_request_duration_seconds Latency of the requests served.\n")
+	b.WriteString("# TYPE revel_request_duration_seconds histogram\n")
+	var cumulative uint64
+	for i, bound := range metricsBuckets {
+		cumulative += atomic.LoadUint64(&m.buckets[i])
+		fmt.Fprintf(b, "revel_request_duration_seconds_bucket{le=\"%g\"} %d\n", bound, cumulative)
+	}
+	count := atomic.LoadUint64(&m.count)
+	fmt.Fprintf(b, "revel_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", count)
+	fmt.Fprintf(b, "revel_request_duration_seconds_sum %g\n", time.Duration(atomic.LoadUint64(&m.durationSum)).Seconds())
+	fmt.Fprintf(b, "revel_request_duration_seconds_count %d\n", count)
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if !metricsEnabled {
+		http.NotFound(w, r)
+		return
+	}
+	var b bytes.Buffer
+	metrics.write(&b)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := b.WriteTo(w); err != nil {
+		ERROR.Println("Response write failed:", err)
+	}
+}
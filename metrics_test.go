@@ -0,0 +1,46 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("server.metrics", "true")
+	initMetrics()
+	defer func() { metricsEnabled = false }()
+	metrics = newRequestMetrics()
+
+	handle(httptest.NewRecorder(), showRequest)
+	handle(httptest.NewRecorder(), plaintextRequest)
+	handle(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing/route", nil))
+
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/_metrics", nil))
+	eq(t, "metrics status", http.StatusOK, resp.Code)
+	body := resp.Body.String()
+	for _, expected := range []string{
+		`revel_requests_total{class="2xx"} 2`,
+		`revel_requests_total{class="4xx"} 1`,
+		`revel_requests_total{class="5xx"} 0`,
+		`revel_request_duration_seconds_bucket{le="+Inf"} 3`,
+		`revel_request_duration_seconds_count 3`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %q in metrics:\n%s", expected, body)
+		}
+	}
+
+	// Disabled metrics are not served
+	metricsEnabled = false
+	resp = httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/_metrics", nil))
+	eq(t, "disabled metrics status", http.StatusNotFound, resp.Code)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"strings"
+)
+
+// TemplateMinifier minifies rendered templates of the content types listed
+// in template.minify.types (text/html by default) when template.minify is
+// enabled, outside of dev mode. Replace it to use a minifier of your own. On
+// error the output is sent as it is.
+//
+// The default minifier collapses runs of whitespace into a single space, or
+// a newline when the run has one, except inside <pre> and <textarea>.
+var TemplateMinifier = MinifyWhitespace
+
+// MinifyWhitespace collapses the runs of whitespace of content, see
+// TemplateMinifier.
+func MinifyWhitespace(contentType string, content []byte) ([]byte, error) {
+	var (
+		out      = make([]byte, 0, len(content))
+		html     = strings.HasPrefix(contentType, "text/html")
+		preserve = "" // The closing tag of the element kept as it is
+		space    byte // The whitespace to write for the current run
+	)
+
+	for i := 0; i < len(content); i++ {
+		if html && content[i] == '<' {
+			if preserve == "" {
+				for _, tag := range []string{"pre", "textarea"} {
+					if rest := content[i+1:]; hasTagPrefix(rest, tag) &&
+						(len(rest) == len(tag) || strings.IndexByte(" \t\r\n>", rest[len(tag)]) >= 0) {
+						preserve = "</" + tag
+					}
+				}
+			} else if hasTagPrefix(content[i:], preserve) {
+				preserve = ""
+			}
+		}
+
+		switch c := content[i]; {
+		case preserve == "" && (c == ' ' || c == '\t' || c == '\n' || c == '\r'):
+			if c == '\n' || space == 0 {
+				space = ' '
+				if c == '\n' {
+					space = '\n'
+				}
+			}
+		default:
+			if space != 0 {
+				out = append(out, space)
+				space = 0
+			}
+			out = append(out, c)
+		}
+	}
+	if space != 0 {
+		out = append(out, space)
+	}
+	return out, nil
+}
+
+// hasTagPrefix reports whether b begins with the tag name, ignoring case.
+func hasTagPrefix(b []byte, tag string) bool {
+	return len(b) >= len(tag) && bytes.EqualFold(b[:len(tag)], []byte(tag))
+}
+
+// minifyTemplate applies the TemplateMinifier to rendered output if
+// template.minify is enabled for the content type.
+func minifyTemplate(contentType string, b *bytes.Buffer) {
+	if DevMode || TemplateMinifier == nil || !Config.BoolDefault("template.minify", false) {
+		return
+	}
+	mimeType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	for _, typ := range strings.Split(Config.StringDefault("template.minify.types", "text/html"), ",") {
+		if strings.TrimSpace(typ) != mimeType {
+			continue
+		}
+		minified, err := TemplateMinifier(contentType, b.Bytes())
+		if err != nil {
+			WARN.Println("Failed to minify the template output:", err)
+			return
+		}
+		b.Reset()
+		b.Write(minified)
+		return
+	}
+}
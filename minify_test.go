@@ -0,0 +1,67 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMinifyWhitespace(t *testing.T) {
+	minified, err := MinifyWhitespace("text/html; charset=utf-8", []byte(
+		"<div>\n    <p>Some  text</p>\t<b>bold</b>\n\n  <pre>  keep\n    this </pre>\n<textarea>a  b</textarea>  </div>\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "minified", string(minified),
+		"<div>\n<p>Some text</p> <b>bold</b>\n<pre>  keep\n    this </pre>\n<textarea>a  b</textarea> </div>\n")
+}
+
+// Test that runes changing length when lowercased don't misplace the tags.
+func TestMinifyWhitespaceNonASCII(t *testing.T) {
+	minified, err := MinifyWhitespace("text/html", []byte(
+		"<p>4  \u212a \u212a \u212a</p>  <PRE>\u2126  \u212b</PRE>  \xff\xfe  <Pre>\xff  x</pre>  end"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "minified", string(minified),
+		"<p>4 \u212a \u212a \u212a</p> <PRE>\u2126  \u212b</PRE> \xff\xfe <Pre>\xff  x</pre> end")
+}
+
+func TestTemplateMinify(t *testing.T) {
+	startFakeBookingApp()
+	render := func() string {
+		resp := httptest.NewRecorder()
+		handle(resp, showRequest)
+		return resp.Body.String()
+	}
+	original := render()
+	if !strings.Contains(original, "\n\n") {
+		t.Fatalf("Expected blank lines in the template output, got %q", original)
+	}
+
+	Config.SetOption("template.minify", "true")
+	minified := render()
+	if strings.Contains(minified, "\n\n") || strings.Contains(minified, "  ") {
+		t.Errorf("Expected the output minified, got %q", minified)
+	}
+	if expected, _ := MinifyWhitespace("text/html", []byte(original)); minified != string(expected) {
+		t.Errorf("Expected the minified output %q, got %q", expected, minified)
+	}
+
+	// A minifier of the app's own
+	defer func(minifier func(string, []byte) ([]byte, error)) { TemplateMinifier = minifier }(TemplateMinifier)
+	TemplateMinifier = func(contentType string, content []byte) ([]byte, error) {
+		return bytes.ToUpper(content), nil
+	}
+	eq(t, "custom minifier", render(), strings.ToUpper(original))
+
+	// Not in dev mode
+	DevMode = true
+	defer func() { DevMode = false }()
+	eq(t, "dev mode", render(), original)
+}
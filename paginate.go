@@ -0,0 +1,58 @@
+// Copyright (c) 2012-2016 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+// PaginationOptions are the defaults and bounds of Paginate.
+type PaginationOptions struct {
+	Page        int // The page without a valid page param, 1 if zero
+	PageSize    int // The page size without a valid pagesize param, 20 if zero
+	MinPageSize int // 1 if zero
+	MaxPageSize int // Zero for no maximum
+}
+
+// Pagination is the page requested of a list.
+type Pagination struct {
+	Page     int // Starting at 1
+	PageSize int
+	Offset   int // Of the first item of the page, starting at 0
+	Limit    int // The number of items of the page, the PageSize
+}
+
+// Paginate reads the page and pagesize params of a list action. Missing or
+// invalid params get the defaults of opts, and the page size is kept within
+// its bounds.
+func (c *Controller) Paginate(opts PaginationOptions) Pagination {
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PageSize < 1 {
+		opts.PageSize = 20
+	}
+	if opts.MinPageSize < 1 {
+		opts.MinPageSize = 1
+	}
+
+	page, err := c.Params.GetInt("page")
+	if err != nil || page < 1 {
+		page = opts.Page
+	}
+	pageSize, err := c.Params.GetInt("pagesize")
+	if err != nil || pageSize < 1 {
+		pageSize = opts.PageSize
+	}
+	if pageSize < opts.MinPageSize {
+		pageSize = opts.MinPageSize
+	}
+	if opts.MaxPageSize > 0 && pageSize > opts.MaxPageSize {
+		pageSize = opts.MaxPageSize
+	}
+
+	return Pagination{
+		Page:     page,
+		PageSize: pageSize,
+		Offset:   (page - 1) * pageSize,
+		Limit:    pageSize,
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2012-2016 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	paginate := func(values url.Values, opts PaginationOptions) Pagination {
+		c := &Controller{Params: &Params{Values: values}}
+		return c.Paginate(opts)
+	}
+	opts := PaginationOptions{PageSize: 25, MinPageSize: 5, MaxPageSize: 100}
+
+	eq(t, "defaults", paginate(nil, PaginationOptions{}),
+		Pagination{Page: 1, PageSize: 20, Offset: 0, Limit: 20})
+	eq(t, "requested", paginate(url.Values{"page": {"3"}, "pagesize": {"10"}}, opts),
+		Pagination{Page: 3, PageSize: 10, Offset: 20, Limit: 10})
+	eq(t, "clamped to max", paginate(url.Values{"page": {"2"}, "pagesize": {"1000"}}, opts),
+		Pagination{Page: 2, PageSize: 100, Offset: 100, Limit: 100})
+	eq(t, "clamped to min", paginate(url.Values{"pagesize": {"2"}}, opts),
+		Pagination{Page: 1, PageSize: 5, Offset: 0, Limit: 5})
+	eq(t, "invalid", paginate(url.Values{"page": {"two"}, "pagesize": {"-4"}}, opts),
+		Pagination{Page: 1, PageSize: 25, Offset: 0, Limit: 25})
+	eq(t, "default page", paginate(url.Values{"page": {"0"}}, PaginationOptions{Page: 2}),
+		Pagination{Page: 2, PageSize: 20, Offset: 20, Limit: 20})
+}
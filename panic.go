@@ -5,6 +5,8 @@
 package revel
 
 import (
+	"fmt"
+	"net/http"
 	"runtime/debug"
 )
 
@@ -19,18 +21,62 @@ func PanicFilter(c *Controller, fc []Filter) {
 	fc[0](c, fc[1:])
 }
 
+var errorHandlers []func(c *Controller, value interface{}) Result
+
+// OnError registers a function given the recovered value when an action
+// panics, e.g. an error of the app's own type. It returns the result to
+// respond with, like c.NotFound for a not found error, or nil to leave the
+// panic to the functions registered after it and finally the 500 error page.
+func OnError(handler func(c *Controller, value interface{}) Result) {
+	errorHandlers = append(errorHandlers, handler)
+}
+
+// panicJSON is the response to a JSON request whose action panicked.
+// Unless errors are disclosed only the generic error message is included.
+type panicJSON struct {
+	Error string `json:"error"`
+	Path  string `json:"path,omitempty"`
+	Line  int    `json:"line,omitempty"`
+	Stack string `json:"stack,omitempty"`
+}
+
 // This function handles a panic in an action invocation.
 // It cleans up the stack trace, logs it, and displays an error page.
 func handleInvocationPanic(c *Controller, err interface{}) {
+	for _, handler := range errorHandlers {
+		if result := handler(c, err); result != nil {
+			c.Result = result
+			return
+		}
+	}
+
 	error := NewErrorFromPanic(err)
-	if error == nil && DevMode {
-		// Only show the sensitive information in the debug stack trace in development mode, not production
+	if error == nil && discloseErrors() && c.Request.Format != "json" {
+		// Only show the sensitive information in the debug stack trace if errors are disclosed
 		ERROR.Print(err, "\n", string(debug.Stack()))
+		c.Response.Out.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		c.Response.Out.WriteHeader(500)
 		_, _ = c.Response.Out.Write(debug.Stack())
 		return
 	}
+	if error == nil {
+		// The panic did not originate from the app code
+		error = &Error{
+			Title:       "Runtime Panic",
+			Description: fmt.Sprint(err),
+			Stack:       limitStack(string(debug.Stack())),
+		}
+	}
 
 	ERROR.Print(err, "\n", error.Stack)
+	if c.Request.Format == "json" {
+		body := panicJSON{Error: http.StatusText(http.StatusInternalServerError)}
+		if discloseErrors() {
+			body = panicJSON{error.Description, error.Path, error.Line, error.Stack}
+		}
+		c.Response.Status = http.StatusInternalServerError
+		c.Result = c.RenderJSON(body)
+		return
+	}
 	c.Result = c.RenderError(error)
 }
@@ -0,0 +1,98 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func panickingFilter(c *Controller, fc []Filter) {
+	panic("boom")
+}
+
+func servePanic(accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/hotels", nil)
+	req.Header.Set("Accept", accept)
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(req), NewResponse(resp))
+	PanicFilter(c, []Filter{panickingFilter})
+	c.Result.Apply(c.Request, c.Response)
+	return resp
+}
+
+func TestPanicHTML(t *testing.T) {
+	startFakeBookingApp()
+	resp := servePanic("text/html")
+	eq(t, "status", http.StatusInternalServerError, resp.Code)
+	if !strings.HasPrefix(resp.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected an HTML error page, got %s", resp.Header().Get("Content-Type"))
+	}
+}
+
+func TestPanicJSON(t *testing.T) {
+	startFakeBookingApp()
+	resp := servePanic("application/json")
+	eq(t, "status", http.StatusInternalServerError, resp.Code)
+	if !strings.HasPrefix(resp.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("Expected a JSON error, got %s", resp.Header().Get("Content-Type"))
+	}
+	var body panicJSON
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse the JSON error %q: %s", resp.Body, err)
+	}
+	eq(t, "prod error", "Internal Server Error", body.Error)
+	eq(t, "prod stack", "", body.Stack)
+
+	DevMode = true
+	defer func() { DevMode = false }()
+	resp = servePanic("application/json")
+	body = panicJSON{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to parse the JSON error %q: %s", resp.Body, err)
+	}
+	eq(t, "dev error", "boom", body.Error)
+	if body.Stack == "" {
+		t.Error("Expected the stack in dev mode")
+	}
+}
+
+type notFoundError struct{ id int }
+
+func (e notFoundError) Error() string { return fmt.Sprintf("hotel %d not found", e.id) }
+
+// Test that the OnError handlers are given the panic value to map to a
+// result, and the 500 page remains for the values they leave alone.
+func TestOnError(t *testing.T) {
+	startFakeBookingApp()
+	defer func() { errorHandlers = nil }()
+	OnError(func(c *Controller, value interface{}) Result {
+		if err, ok := value.(notFoundError); ok {
+			return c.NotFound(err.Error())
+		}
+		return nil
+	})
+
+	serve := func(filter Filter) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(httptest.NewRequest("GET", "/hotels/3", nil)), NewResponse(resp))
+		PanicFilter(c, []Filter{filter})
+		c.Result.Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := serve(func(c *Controller, fc []Filter) { panic(notFoundError{3}) })
+	eq(t, "mapped status", resp.Code, http.StatusNotFound)
+	if !strings.Contains(resp.Body.String(), "hotel 3 not found") {
+		t.Errorf("Expected the error message in the page, got %q", resp.Body.String())
+	}
+
+	resp = serve(panickingFilter)
+	eq(t, "unmapped status", resp.Code, http.StatusInternalServerError)
+}
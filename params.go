@@ -5,21 +5,37 @@
 package revel
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"mime/multipart"
+	"net/http"
 	"net/url"
 	"os"
 	"reflect"
 	"errors"
+	"strconv"
+	"strings"
+	"sync"
 )
 
+// ErrParamNotFound is returned by the typed getters of Params when the
+// requested param is not present.
+var ErrParamNotFound = errors.New("revel/params: param not found")
+
 // Params provides a unified view of the request params.
 // Includes:
 // - URL query string
 // - Form values
 // - File uploads
 //
+// When a param is given by several sources, the unified view only holds the
+// values of the one with the highest precedence. By default that is
+// fixed > route > form (body) > query, which may be changed in app.conf:
+//
+//	params.precedence = fixed,route,query,form
+//
+// Sources left out of the setting follow the listed ones in default order.
 // Warning: param maps other than Values may be nil if there were none.
 type Params struct {
 	url.Values // A unified view of all the individual param maps below.
@@ -30,16 +46,35 @@ type Params struct {
 
 	// Set by the ParamsFilter
 	Query url.Values // Parameters from the query string, e.g. /index?limit=10
-	Form  url.Values // Parameters from the request body.
+	Form  url.Values // Parameters from the request body, without the query string ones.
 
 	Files    map[string][]*multipart.FileHeader // Files uploaded in a multipart form
 	tmpFiles []*os.File                         // Temp files used during the request.
 	JSON     []byte                             // JSON data from request body
+	jsonErr  error                              // Failure binding JSON in format.json.strict mode
+	header   http.Header                        // Request headers, for the fields tagged header:"Name"
+
+	bindErrors []*BindError // The params which failed to convert when bound
+}
+
+const defaultParamsPrecedence = "fixed,route,form,query"
+
+var (
+	// The names of the param sources in order of precedence, see
+	// loadParamsPrecedence
+	paramsPrecedence     = strings.Split(defaultParamsPrecedence, ",")
+	paramsPrecedenceLock sync.RWMutex
+)
+
+func init() {
+	OnAppStart(loadParamsPrecedence)
+	OnConfigReload(loadParamsPrecedence)
 }
 
 // ParseParams parses the `http.Request` params into `revel.Controller.Params`
 func ParseParams(params *Params, req *Request) {
 	params.Query = req.URL.Query()
+	params.header = req.Header
 
 	// Parse the body depending on the content type.
 	switch req.ContentType {
@@ -48,13 +83,15 @@ func ParseParams(params *Params, req *Request) {
 		if err := req.ParseForm(); err != nil {
 			WARN.Println("Error parsing request body:", err)
 		} else {
-			params.Form = req.Form
+			// The body values only, Form holds the query values too.
+			params.Form = req.PostForm
 		}
 
 	case "multipart/form-data":
-		// Multipart form.
-		// TODO: Extract the multipart form param so app can set it.
-		if err := req.ParseMultipartForm(32 << 20 /* 32 MB */); err != nil {
+		// Multipart form. The files beyond upload.maxmemory bytes are stored in
+		// temp files, which http.maxrequestsize doesn't limit on its own.
+		maxMemory := int64(Config.IntDefault("upload.maxmemory", 32<<20 /* 32 MB */))
+		if err := req.ParseMultipartForm(maxMemory); err != nil {
 			WARN.Println("Error parsing request body:", err)
 		} else {
 			params.Form = req.MultipartForm.Value
@@ -108,13 +145,75 @@ func (p *Params) BindJSON(dest interface{}) error {
 		WARN.Println("BindJSON not a pointer")
 		return errors.New("BindJSON not a pointer")
 	}
-	if err := json.Unmarshal(p.JSON, dest); err != nil {
+	if err := unmarshalJSON(p.JSON, dest); err != nil {
 		WARN.Println("W: bindMap: Unable to unmarshal request:", err)
 		return err
 	}
 	return nil
 }
 
+// unmarshalJSON decodes the JSON request body into dest. With
+// format.json.strict fields of the body which dest doesn't have are an error.
+func unmarshalJSON(data []byte, dest interface{}) error {
+	if !Config.BoolDefault("format.json.strict", false) {
+		return json.Unmarshal(data, dest)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(dest)
+}
+
+// bindJSON binds the JSON request body to dest for the binders. A failure is
+// kept in strict mode, for the ActionInvoker to refuse the request.
+func (p *Params) bindJSON(name string, dest interface{}) {
+	if err := unmarshalJSON(p.JSON, dest); err != nil {
+		WARN.Println("W: Unable to unmarshal request:", name, err)
+		if Config.BoolDefault("format.json.strict", false) && p.jsonErr == nil {
+			p.jsonErr = err
+		}
+	}
+}
+
+// GetInt returns the named param as an int. The error is ErrParamNotFound if
+// the param is not present, or the parse error if it is not an int.
+func (p *Params) GetInt(name string) (int, error) {
+	value, err := p.get(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// GetBool returns the named param as a bool, accepting the values of
+// strconv.ParseBool. The error is ErrParamNotFound if the param is not
+// present, or the parse error if it is not a bool.
+func (p *Params) GetBool(name string) (bool, error) {
+	value, err := p.get(name)
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+// GetFloat returns the named param as a float64. The error is
+// ErrParamNotFound if the param is not present, or the parse error if it is
+// not a number.
+func (p *Params) GetFloat(name string) (float64, error) {
+	value, err := p.get(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// get returns the first value of the named param.
+func (p *Params) get(name string) (string, error) {
+	if values := p.Values[name]; len(values) > 0 {
+		return values[0], nil
+	}
+	return "", ErrParamNotFound
+}
+
 // calcValues returns a unified view of the component param maps.
 func (p *Params) calcValues() url.Values {
 	numParams := len(p.Query) + len(p.Fixed) + len(p.Route) + len(p.Form)
@@ -124,40 +223,62 @@ func (p *Params) calcValues() url.Values {
 		return make(url.Values, 0)
 	}
 
-	// If only one of the param sources has anything, return that directly.
-	switch numParams {
-	case len(p.Query):
-		return p.Query
-	case len(p.Route):
-		return p.Route
-	case len(p.Fixed):
-		return p.Fixed
-	case len(p.Form):
-		return p.Form
+	// Copy everything into a param map, the first source to have a param
+	// (in order of precedence) provides its values. The values are copied
+	// so that changing the unified view leaves the sources alone.
+	values := make(url.Values, numParams)
+	for _, source := range p.sources() {
+		for k, v := range source {
+			if _, found := values[k]; !found {
+				values[k] = append([]string(nil), v...)
+			}
+		}
 	}
+	return values
+}
 
-	// Copy everything into a param map,
-	// order of priority is least to most trusted
-	values := make(url.Values, numParams)
+// sources returns the param maps in order of precedence, see Params.
+func (p *Params) sources() []url.Values {
+	byName := map[string]url.Values{"fixed": p.Fixed, "route": p.Route, "form": p.Form, "query": p.Query}
+	paramsPrecedenceLock.RLock()
+	precedence := paramsPrecedence
+	paramsPrecedenceLock.RUnlock()
 
-	// ?query vars first
-	for k, v := range p.Query {
-		values[k] = append(values[k], v...)
-	}
-	// form vars overwrite
-	for k, v := range p.Form {
-		values[k] = append(values[k], v...)
-	}
-	// :/path vars overwrite
-	for k, v := range p.Route {
-		values[k] = append(values[k], v...)
+	sources := make([]url.Values, len(precedence))
+	for i, name := range precedence {
+		sources[i] = byName[name]
 	}
-	// fixed vars overwrite
-	for k, v := range p.Fixed {
-		values[k] = append(values[k], v...)
+	return sources
+}
+
+// loadParamsPrecedence reads params.precedence, warning of the unknown
+// sources once rather than on every request.
+func loadParamsPrecedence() {
+	aliases := map[string]string{"path": "route", "body": "form"}
+	known := map[string]bool{"fixed": true, "route": true, "form": true, "query": true}
+
+	var precedence []string
+	added := map[string]bool{}
+	setting := Config.StringDefault("params.precedence", "")
+	for _, name := range strings.Split(setting+","+defaultParamsPrecedence, ",") {
+		name = strings.TrimSpace(name)
+		if alias, found := aliases[name]; found {
+			name = alias
+		}
+		if name == "" || added[name] {
+			continue
+		}
+		if !known[name] {
+			WARN.Println("Unknown source in params.precedence:", name)
+			continue
+		}
+		precedence = append(precedence, name)
+		added[name] = true
 	}
 
-	return values
+	paramsPrecedenceLock.Lock()
+	paramsPrecedence = precedence
+	paramsPrecedenceLock.Unlock()
 }
 
 func ParamsFilter(c *Controller, fc []Filter) {
@@ -8,9 +8,13 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
+	"os"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -99,6 +103,7 @@ func BenchmarkParams(b *testing.B) {
 }
 
 func TestMultipartForm(t *testing.T) {
+	startFakeBookingApp()
 	c := Controller{
 		Request: NewRequest(getMultipartRequest()),
 		Params:  &Params{},
@@ -124,6 +129,48 @@ func TestMultipartForm(t *testing.T) {
 	}
 }
 
+// Test that the files beyond upload.maxmemory are stored in temp files.
+func TestUploadMaxMemory(t *testing.T) {
+	startFakeBookingApp()
+	tmpDir, err := ioutil.TempDir("", "revel-upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	defer func(dir string) { _ = os.Setenv("TMPDIR", dir) }(os.Getenv("TMPDIR"))
+	_ = os.Setenv("TMPDIR", tmpDir)
+
+	parse := func() (*Params, int) {
+		req := NewRequest(getMultipartRequest())
+		params := &Params{}
+		ParseParams(params, req)
+		tmpFiles, err := ioutil.ReadDir(tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return params, len(tmpFiles)
+	}
+
+	if _, spilled := parse(); spilled != 0 {
+		t.Errorf("Expected the upload in memory, got %d temp files", spilled)
+	}
+
+	Config.SetOption("upload.maxmemory", "10")
+	defer Config.SetOption("upload.maxmemory", strconv.Itoa(32<<20))
+	params, spilled := parse()
+	if spilled == 0 {
+		t.Error("Expected the upload over the memory limit in a temp file")
+	}
+	eq(t, "field", params.Get("text1"), "data1")
+	file, err := params.Files["file1"][0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+	content, _ := ioutil.ReadAll(file)
+	eq(t, "file content", string(content), "content1")
+}
+
 func TestBind(t *testing.T) {
 	params := Params{
 		Values: url.Values{
@@ -137,6 +184,107 @@ func TestBind(t *testing.T) {
 	}
 }
 
+func TestParamsTypedGetters(t *testing.T) {
+	params := Params{
+		Values: url.Values{
+			"page":   {"3"},
+			"active": {"true"},
+			"price":  {"9.75"},
+			"bad":    {"abc"},
+		},
+	}
+
+	page, err := params.GetInt("page")
+	eq(t, "int", page, 3)
+	eq(t, "int error", err, nil)
+	active, err := params.GetBool("active")
+	eq(t, "bool", active, true)
+	eq(t, "bool error", err, nil)
+	price, err := params.GetFloat("price")
+	eq(t, "float", price, 9.75)
+	eq(t, "float error", err, nil)
+
+	if _, err = params.GetInt("bad"); err == nil || err == ErrParamNotFound {
+		t.Errorf("Expected a parse error for an invalid int, got %v", err)
+	}
+	if _, err = params.GetBool("bad"); err == nil || err == ErrParamNotFound {
+		t.Errorf("Expected a parse error for an invalid bool, got %v", err)
+	}
+	if _, err = params.GetFloat("bad"); err == nil || err == ErrParamNotFound {
+		t.Errorf("Expected a parse error for an invalid float, got %v", err)
+	}
+
+	_, err = params.GetInt("missing")
+	eq(t, "missing int", err, ErrParamNotFound)
+	_, err = params.GetBool("missing")
+	eq(t, "missing bool", err, ErrParamNotFound)
+	_, err = params.GetFloat("missing")
+	eq(t, "missing float", err, ErrParamNotFound)
+}
+
+func TestParamsPrecedence(t *testing.T) {
+	startFakeBookingApp()
+	params := &Params{
+		Route: url.Values{"id": {"route"}},
+		Query: url.Values{"id": {"query"}, "page": {"2"}, "sort": {"query"}},
+		Form:  url.Values{"id": {"form"}, "sort": {"form"}},
+	}
+	values := params.calcValues()
+	eq(t, "route over form and query", "route", values.Get("id"))
+	eq(t, "form over query", "form", values.Get("sort"))
+	eq(t, "query only", "2", values.Get("page"))
+	eq(t, "values of one source", 1, len(values["id"]))
+
+	Config.SetOption("params.precedence", "query,path")
+	loadParamsPrecedence()
+	defer func() {
+		Config.SetOption("params.precedence", "")
+		loadParamsPrecedence()
+	}()
+	values = params.calcValues()
+	eq(t, "configured query first", "query", values.Get("id"))
+	eq(t, "unlisted source last", "query", values.Get("sort"))
+}
+
+// Test that params.precedence is checked once, not on every request.
+func TestParamsPrecedenceUnknown(t *testing.T) {
+	startFakeBookingApp()
+	var warned bytes.Buffer
+	WARN = log.New(&warned, "", 0)
+	defer func() {
+		Config.SetOption("params.precedence", "")
+		loadParamsPrecedence()
+	}()
+
+	Config.SetOption("params.precedence", "")
+	loadParamsPrecedence()
+	eq(t, "empty setting warning", warned.String(), "")
+	eq(t, "empty setting", strings.Join(paramsPrecedence, ","), "fixed,route,form,query")
+
+	Config.SetOption("params.precedence", "query, typo")
+	loadParamsPrecedence()
+	eq(t, "unknown source warning", warned.String(), "Unknown source in params.precedence: typo\n")
+	eq(t, "unknown source skipped", strings.Join(paramsPrecedence, ","), "query,fixed,route,form")
+
+	params := &Params{Query: url.Values{"id": {"query"}}, Form: url.Values{"id": {"form"}}}
+	eq(t, "query first", params.calcValues().Get("id"), "query")
+	eq(t, "warned once", strings.Count(warned.String(), "\n"), 1)
+}
+
+func TestParamsValuesCopied(t *testing.T) {
+	startFakeBookingApp()
+	for _, params := range []*Params{
+		{Query: url.Values{"id": {"query"}}},
+		{Query: url.Values{"id": {"query"}}, Form: url.Values{"name": {"form"}}},
+	} {
+		values := params.calcValues()
+		values["id"][0] = "changed"
+		values.Add("id", "added")
+		values.Set("other", "added")
+		eq(t, "query values", params.Query.Encode(), "id=query")
+	}
+}
+
 func TestResolveAcceptLanguage(t *testing.T) {
 	request := buildHTTPRequestWithAcceptLanguage("")
 	if result := ResolveAcceptLanguage(request); result != nil {
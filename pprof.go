@@ -0,0 +1,74 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// The net/http/pprof handlers, served ahead of the filter chain when enabled
+// in app.conf:
+//
+//	server.pprof      = true
+//	server.pprof.path = /debug/pprof/
+//	server.pprof.auth = user:password
+//
+// When server.pprof.auth is set the handlers require these basic auth
+// credentials, which is strongly recommended outside of development.
+var pprofEnabled bool
+
+func init() {
+	OnAppStart(initPprof)
+}
+
+// initPprof registers the pprof handlers if enabled.
+func initPprof() {
+	pprofEnabled = Config.BoolDefault("server.pprof", false)
+	if pprofEnabled {
+		AddHTTPMux(pprofPath(), http.HandlerFunc(pprofHandler))
+	}
+}
+
+// pprofPath returns the configured path, which always ends in a slash.
+func pprofPath() string {
+	path := Config.StringDefault("server.pprof.path", "/debug/pprof/")
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return path
+}
+
+func pprofHandler(w http.ResponseWriter, r *http.Request) {
+	if !pprofEnabled {
+		http.NotFound(w, r)
+		return
+	}
+	if auth := Config.StringDefault("server.pprof.auth", ""); auth != "" {
+		user, password, _ := r.BasicAuth()
+		if subtle.ConstantTimeCompare([]byte(user+":"+password), []byte(auth)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="pprof"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	switch name := strings.TrimPrefix(r.URL.Path, pprofPath()); name {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		// The index expects to be served below /debug/pprof/
+		r.URL.Path = "/debug/pprof/" + name
+		pprof.Index(w, r)
+	}
+}
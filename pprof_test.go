@@ -0,0 +1,49 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPprofDisabled(t *testing.T) {
+	startFakeBookingApp()
+	initPprof()
+
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	eq(t, "disabled status", http.StatusNotFound, resp.Code)
+}
+
+func TestPprofEnabled(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("server.pprof", "true")
+	Config.SetOption("server.pprof.path", "/_pprof")
+	Config.SetOption("server.pprof.auth", "admin:secret")
+	initPprof()
+	defer func() { pprofEnabled = false }()
+
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/_pprof/", nil))
+	eq(t, "unauthorized status", http.StatusUnauthorized, resp.Code)
+
+	req := httptest.NewRequest("GET", "/_pprof/", nil)
+	req.SetBasicAuth("admin", "secret")
+	resp = httptest.NewRecorder()
+	handle(resp, req)
+	eq(t, "index status", http.StatusOK, resp.Code)
+	if !strings.Contains(resp.Body.String(), "goroutine") {
+		t.Errorf("Expected the profile index, got:\n%s", resp.Body)
+	}
+
+	req = httptest.NewRequest("GET", "/_pprof/cmdline", nil)
+	req.SetBasicAuth("admin", "secret")
+	resp = httptest.NewRecorder()
+	handle(resp, req)
+	eq(t, "cmdline status", http.StatusOK, resp.Code)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The value logged in place of a redacted one.
+const redactedValue = "***"
+
+// requestLogDetails returns what the access log shows of the request besides
+// the path: the query if log.request.query is set, and the headers listed in
+// log.request.headers. The values of the params and headers named in
+// log.redact are masked, e.g.
+//
+//	log.request.query   = true
+//	log.request.headers = User-Agent, Authorization
+//	log.redact          = token, email, Authorization
+func requestLogDetails(r *http.Request) string {
+	redact := map[string]bool{}
+	for _, name := range strings.Split(Config.StringDefault("log.redact", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			redact[strings.ToLower(name)] = true
+		}
+	}
+
+	var details string
+	if Config.BoolDefault("log.request.query", false) && r.URL.RawQuery != "" {
+		details = "?" + redactQuery(r.URL.RawQuery, redact)
+	}
+	for _, name := range strings.Split(Config.StringDefault("log.request.headers", ""), ",") {
+		name = strings.TrimSpace(name)
+		value := r.Header.Get(name)
+		if name == "" || value == "" {
+			continue
+		}
+		if redact[strings.ToLower(name)] {
+			value = redactedValue
+		}
+		details += " " + http.CanonicalHeaderKey(name) + "=" + value
+	}
+	return details
+}
+
+// redactQuery masks the values of the redacted params of the raw query,
+// keeping the rest of it as it was sent.
+func redactQuery(rawQuery string, redact map[string]bool) string {
+	if len(redact) == 0 {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key := param
+		if index := strings.Index(param, "="); index >= 0 {
+			key = param[:index]
+		}
+		if name, err := url.QueryUnescape(key); err == nil && redact[strings.ToLower(name)] {
+			params[i] = key + "=" + redactedValue
+		}
+	}
+	return strings.Join(params, "&")
+}
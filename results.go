@@ -10,6 +10,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"html/template"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -17,6 +18,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/net/websocket"
 )
@@ -27,7 +29,8 @@ type Result interface {
 
 // ErrorResult structure used to handles all kinds of error codes (500, 404, ..).
 // It renders the relevant error page (errors/CODE.format, e.g. errors/500.json).
-// If RunMode is "dev", this results in a friendly error page.
+// If RunMode is "dev", this results in a friendly error page. Whether the
+// stack trace and source context are shown is controlled by errors.disclose.
 type ErrorResult struct {
 	ViewArgs map[string]interface{}
 	Error      error
@@ -49,6 +52,12 @@ func (r ErrorResult) Apply(req *Request, resp *Response) {
 	var err error
 	templatePath := fmt.Sprintf("errors/%d.%s", status, format)
 	tmpl, err := MainTemplateLoader.TemplateLang(templatePath, lang)
+	if tmpl == nil {
+		// Statuses without a template of their own share a generic one
+		if generic, _ := MainTemplateLoader.TemplateLang("errors/error."+format, lang); generic != nil {
+			tmpl, err = generic, nil
+		}
+	}
 
 	// This func shows a plaintext error message, in case the template rendering
 	// doesn't work.
@@ -86,6 +95,7 @@ func (r ErrorResult) Apply(req *Request, resp *Response) {
 		r.ViewArgs = make(map[string]interface{})
 	}
 	r.ViewArgs["RunMode"] = RunMode
+	r.ViewArgs["Disclose"] = discloseErrors()
 	r.ViewArgs["Error"] = revelError
 	r.ViewArgs["Router"] = MainRouter
 
@@ -106,6 +116,8 @@ func (r ErrorResult) Apply(req *Request, resp *Response) {
 			ERROR.Println("Send failed:", err)
 		}
 	} else {
+		// The template depends on the format accepted
+		resp.AppendVary("Accept")
 		resp.WriteHeader(status, contentType)
 		if _, err := b.WriteTo(resp.Out); err != nil {
 			ERROR.Println("Response WriteTo failed:", err)
@@ -129,8 +141,45 @@ func (r PlaintextErrorResult) Apply(req *Request, resp *Response) {
 // RenderTemplateResult action methods returns this result to request
 // a template be rendered.
 type RenderTemplateResult struct {
-	Template   Template
+	Template Template
 	ViewArgs map[string]interface{}
+
+	// The template the rendered Template is wrapped in, given as .content
+	Layout Template
+
+	// Whether the layout up to the content is flushed before the Template is
+	// rendered, see Controller.RenderStream
+	Stream bool
+
+	// The controller rendering, passed to the template context funcs
+	controller *Controller
+}
+
+var templateContextFuncs []func(*Controller) map[string]interface{}
+
+// TemplateContextFunc registers a func whose values are added to the
+// arguments of every template rendered, e.g. the current user. The values
+// set by the action win over those of the func, and the funcs registered
+// later win over the earlier ones.
+func TemplateContextFunc(fn func(*Controller) map[string]interface{}) {
+	templateContextFuncs = append(templateContextFuncs, fn)
+}
+
+// args returns the ViewArgs merged into the template context.
+func (r *RenderTemplateResult) args() map[string]interface{} {
+	if len(templateContextFuncs) == 0 || r.controller == nil {
+		return r.ViewArgs
+	}
+	args := make(map[string]interface{}, len(r.ViewArgs))
+	for _, fn := range templateContextFuncs {
+		for k, v := range fn(r.controller) {
+			args[k] = v
+		}
+	}
+	for k, v := range r.ViewArgs {
+		args[k] = v
+	}
+	return args
 }
 
 func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
@@ -151,11 +200,16 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 		out = ioutil.Discard
 	}
 
+	if r.Stream {
+		r.stream(req, resp, out)
+		return
+	}
+
 	// In a prod mode, write the status, render, and hope for the best.
 	// (In a dev mode, always render to a temporary buffer first to avoid having
 	// error pages distorted by HTML already written)
 	if chunked && !DevMode {
-		resp.WriteHeader(http.StatusOK, "text/html; charset=utf-8")
+		resp.WriteHeader(http.StatusOK, r.contentType())
 		r.render(req, resp, out)
 		return
 	}
@@ -165,7 +219,10 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 	// Otherwise, template render errors may result in unpredictable HTML (and
 	// would carry a 200 status code)
 	var b bytes.Buffer
-	r.render(req, resp, &b)
+	if err := r.render(req, resp, &b); err != nil {
+		// The error page has been rendered instead, drop the partial output.
+		return
+	}
 
 	// Trimming the HTML will do the following:
 	// * Remove all leading & trailing whitespace on every line
@@ -214,21 +271,38 @@ func (r *RenderTemplateResult) Apply(req *Request, resp *Response) {
 		// Replace the buffer
 		b = b2
 	}
+	minifyTemplate(r.contentType(), &b)
 
 	if !chunked {
 		resp.Out.Header().Set("Content-Length", strconv.Itoa(b.Len()))
 	}
-	resp.WriteHeader(http.StatusOK, "text/html; charset=utf-8")
+	resp.WriteHeader(http.StatusOK, r.contentType())
 	if _, err := b.WriteTo(out); err != nil {
 		ERROR.Println("Response write failed:", err)
 	}
 }
 
-func (r *RenderTemplateResult) render(req *Request, resp *Response, wr io.Writer) {
-	err := r.Template.Render(wr, r.ViewArgs)
-	if err == nil {
-		return
+// contentType returns the content type of the template's file extension, so
+// that e.g. a .txt or .xml template is served as such. It defaults to HTML.
+func (r *RenderTemplateResult) contentType() string {
+	if contentType := ContentTypeByFilename(r.Template.Name()); contentType != DefaultFileContentType {
+		return contentType
+	}
+	return "text/html; charset=utf-8"
+}
+
+// render executes the template into wr. On failure the error page is
+// applied to the response instead and the execution error is returned.
+func (r *RenderTemplateResult) render(req *Request, resp *Response, wr io.Writer) error {
+	if err := r.execute(wr); err != nil {
+		return r.renderError(req, resp, err)
 	}
+	return nil
+}
+
+// renderError applies the error page of the template execution error to the
+// response and returns it.
+func (r *RenderTemplateResult) renderError(req *Request, resp *Response, err error) error {
 
 	var templateContent []string
 	templateName, line, description := ParseTemplateError(err)
@@ -251,6 +325,71 @@ func (r *RenderTemplateResult) render(req *Request, resp *Response, wr io.Writer
 	resp.Status = 500
 	ERROR.Printf("Template Execution Error (in %s): %s", templateName, description)
 	ErrorResult{r.ViewArgs, compileError}.Apply(req, resp)
+	return compileError
+}
+
+// execute renders the template into wr, wrapped in the layout if there is
+// one.
+func (r *RenderTemplateResult) execute(wr io.Writer) error {
+	args := r.args()
+	if r.Layout == nil {
+		return r.Template.Render(wr, args)
+	}
+
+	var content bytes.Buffer
+	if err := r.Template.Render(&content, args); err != nil {
+		return err
+	}
+	return r.Layout.Render(wr, layoutArgs(args, template.HTML(content.String())))
+}
+
+// layoutArgs returns the args of a layout wrapping the content.
+func layoutArgs(args map[string]interface{}, content template.HTML) map[string]interface{} {
+	withContent := make(map[string]interface{}, len(args)+1)
+	for k, v := range args {
+		withContent[k] = v
+	}
+	withContent["content"] = content
+	return withContent
+}
+
+// streamedContent marks the place of the content in the layout of a
+// streamed template.
+const streamedContent = "\x00revel:content\x00"
+
+// stream writes the layout up to the content and flushes it, then renders
+// the template straight into out, followed by the rest of the layout. The
+// layout is rendered first, so that its errors still get an error page.
+// Errors of the template are only logged, since the response has started.
+func (r *RenderTemplateResult) stream(req *Request, resp *Response, out io.Writer) {
+	args := r.args()
+	var prefix, suffix []byte
+	if r.Layout != nil {
+		var b bytes.Buffer
+		if err := r.Layout.Render(&b, layoutArgs(args, streamedContent)); err != nil {
+			_ = r.renderError(req, resp, err)
+			return
+		}
+		prefix = b.Bytes()
+		if i := bytes.Index(prefix, []byte(streamedContent)); i >= 0 {
+			prefix, suffix = prefix[:i], prefix[i+len(streamedContent):]
+		}
+	}
+
+	resp.WriteHeader(http.StatusOK, r.contentType())
+	if _, err := out.Write(prefix); err != nil {
+		ERROR.Println("Response write failed:", err)
+		return
+	}
+	resp.Flush()
+
+	if err := r.Template.Render(out, args); err != nil {
+		ERROR.Printf("Template Execution Error (in %s, streamed): %s", r.Template.Name(), err)
+		return
+	}
+	if _, err := out.Write(suffix); err != nil {
+		ERROR.Println("Response write failed:", err)
+	}
 }
 
 type RenderHTMLResult struct {
@@ -278,7 +417,12 @@ func (r RenderJSONResult) Apply(req *Request, resp *Response) {
 		b, err = json.Marshal(r.obj)
 	}
 
+	// Nothing has been written yet, so the failure gets a clean error page
+	// rather than the status and type meant for the JSON
 	if err != nil {
+		ERROR.Println("JSON marshal failed:", err)
+		resp.Status = http.StatusInternalServerError
+		resp.ContentType = ""
 		ErrorResult{Error: err}.Apply(req, resp)
 		return
 	}
@@ -327,6 +471,61 @@ func (r RenderXMLResult) Apply(req *Request, resp *Response) {
 	}
 }
 
+// codec encodes objects for RenderNegotiated as a content type that is not
+// built in.
+type codec struct {
+	contentType string
+	encode      func(io.Writer, interface{}) error
+}
+
+var codecs []codec
+
+// RegisterCodec adds an encoding consulted by RenderNegotiated: a request
+// that accepts contentType has the object written by encode. Registering a
+// content type again replaces its encode function.
+func RegisterCodec(contentType string, encode func(io.Writer, interface{}) error) {
+	for i := range codecs {
+		if codecs[i].contentType == contentType {
+			codecs[i].encode = encode
+			return
+		}
+	}
+	codecs = append(codecs, codec{contentType, encode})
+}
+
+// acceptedCodec returns the first registered codec whose content type is
+// in the Accept header.
+func acceptedCodec(accept string) (codec, bool) {
+	for _, c := range codecs {
+		if strings.Contains(accept, c.contentType) {
+			return c, true
+		}
+	}
+	return codec{}, false
+}
+
+type RenderCodecResult struct {
+	obj   interface{}
+	codec codec
+}
+
+func (r RenderCodecResult) Apply(req *Request, resp *Response) {
+	// Encode into a buffer, so that a failure still gets an error page
+	var b bytes.Buffer
+	if err := r.codec.encode(&b, r.obj); err != nil {
+		ERROR.Println("Encoding", r.codec.contentType, "failed:", err)
+		resp.Status = http.StatusInternalServerError
+		resp.ContentType = ""
+		ErrorResult{Error: err}.Apply(req, resp)
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK, r.codec.contentType)
+	if _, err := resp.Out.Write(b.Bytes()); err != nil {
+		ERROR.Println("Response write failed:", err)
+	}
+}
+
 type RenderTextResult struct {
 	text string
 }
@@ -338,6 +537,17 @@ func (r RenderTextResult) Apply(req *Request, resp *Response) {
 	}
 }
 
+// NoContentResult writes an HTTP 204 No Content response, which has neither
+// a body nor a content type.
+type NoContentResult struct{}
+
+func (r NoContentResult) Apply(req *Request, resp *Response) {
+	resp.Status = http.StatusNoContent
+	resp.Out.Header().Del("Content-Type")
+	resp.Out.Header().Del("Content-Length")
+	resp.Out.WriteHeader(http.StatusNoContent)
+}
+
 type ContentDisposition string
 
 var (
@@ -353,12 +563,13 @@ type BinaryResult struct {
 	ModTime  time.Time
 }
 
+// Apply writes the content to the response. Writes time out after the
+// configured results.write.timeout (in seconds) without progress, aborting
+// the downloads of stalled clients.
 func (r *BinaryResult) Apply(req *Request, resp *Response) {
-	disposition := string(r.Delivery)
-	if r.Name != "" {
-		disposition += fmt.Sprintf(`; filename="%s"`, r.Name)
-	}
-	resp.Out.Header().Set("Content-Disposition", disposition)
+	defer withWriteDeadline(req, resp)()
+
+	resp.Out.Header().Set("Content-Disposition", contentDisposition(r.Delivery, r.Name))
 
 	// If we have a ReadSeeker, delegate to http.ServeContent
 	if rs, ok := r.Reader.(io.ReadSeeker); ok {
@@ -387,13 +598,36 @@ func (r *BinaryResult) Apply(req *Request, resp *Response) {
 	}
 }
 
+// ReaderResult streams a reader of any content to the client, e.g. the body
+// of a proxied upstream response.
+type ReaderResult struct {
+	ContentType string
+	Reader      io.Reader
+}
+
+// Apply copies the reader to the response, closing it afterwards if it is an
+// io.Closer. Writes time out like those of the BinaryResult.
+func (r *ReaderResult) Apply(req *Request, resp *Response) {
+	defer withWriteDeadline(req, resp)()
+	if closer, ok := r.Reader.(io.Closer); ok {
+		defer func() {
+			_ = closer.Close()
+		}()
+	}
+
+	resp.WriteHeader(http.StatusOK, r.ContentType)
+	if _, err := io.Copy(resp.Out, r.Reader); err != nil {
+		ERROR.Println("Response write failed:", err)
+	}
+}
+
 type RedirectToURLResult struct {
 	url string
 }
 
 func (r *RedirectToURLResult) Apply(req *Request, resp *Response) {
 	resp.Out.Header().Set("Location", r.url)
-	resp.WriteHeader(http.StatusFound, "")
+	resp.WriteHeader(http.StatusFound, "text/plain; charset=utf-8")
 }
 
 type RedirectToActionResult struct {
@@ -408,7 +642,52 @@ func (r *RedirectToActionResult) Apply(req *Request, resp *Response) {
 		return
 	}
 	resp.Out.Header().Set("Location", url)
-	resp.WriteHeader(http.StatusFound, "")
+	resp.WriteHeader(http.StatusFound, "text/plain; charset=utf-8")
+}
+
+// contentDisposition returns the Content-Disposition header of the delivery
+// of the file name. Names which are not plain ASCII are sent RFC 5987 encoded
+// in filename*, along with an ASCII approximation for older clients.
+func contentDisposition(delivery ContentDisposition, name string) string {
+	disposition := string(delivery)
+	if name == "" {
+		return disposition
+	}
+
+	var ascii, encoded bytes.Buffer
+	plain := true
+	for _, r := range name {
+		switch {
+		case r >= utf8.RuneSelf || r < ' ' || r == 0x7f:
+			plain = false
+			ascii.WriteByte('_')
+		case r == '"' || r == '\\':
+			ascii.WriteByte('\\')
+			ascii.WriteRune(r)
+		default:
+			ascii.WriteRune(r)
+		}
+	}
+	disposition += `; filename="` + ascii.String() + `"`
+	if plain {
+		return disposition
+	}
+
+	for i := 0; i < len(name); i++ {
+		if b := name[i]; b < utf8.RuneSelf && isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return disposition + "; filename*=UTF-8''" + encoded.String()
+}
+
+// isAttrChar reports whether the byte may be sent unencoded in an RFC 5987
+// extended parameter value.
+func isAttrChar(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+		strings.IndexByte("!#$&+-.^_`|~", b) >= 0
 }
 
 func getRedirectURL(item interface{}) (string, error) {
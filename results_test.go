@@ -5,9 +5,20 @@
 package revel
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Test that the render response is as expected.
@@ -25,6 +36,311 @@ func TestBenchmarkRender(t *testing.T) {
 	}
 }
 
+// Test that a trailer set on the response is received by the client.
+func TestResponseTrailer(t *testing.T) {
+	startFakeBookingApp()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := NewController(NewRequest(r), NewResponse(w))
+		c.Response.SetTrailer("grpc-status", "0")
+		c.Response.Out.Header().Set("Content-Length", "13")
+		c.RenderText("Hello, World!").Apply(c.Request, c.Response)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Reading body failed: %s", err)
+	}
+	if string(body) != "Hello, World!" {
+		t.Errorf("Unexpected body: %s", body)
+	}
+	if trailer := resp.Trailer.Get("Grpc-Status"); trailer != "0" {
+		t.Errorf("Expected trailer Grpc-Status to be 0, got %q (trailers %v)", trailer, resp.Trailer)
+	}
+}
+
+// Test that a template failing at execution time renders a clean 500 page.
+func TestRenderTemplateExecutionError(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.ViewArgs["hotel"] = &Hotel{3, "A Hotel", "300 Main St.", "New York", "NY", "10010", "USA", 300}
+	c.RenderTemplate("hotels/broken.html").Apply(c.Request, c.Response)
+
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", resp.Code)
+	}
+	if strings.Contains(resp.Body.String(), "Partial output") {
+		t.Errorf("Found partial template output in error response:\n%s", resp.Body)
+	}
+}
+
+// Test that text is rendered with the given status.
+func TestRenderTextWithStatus(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.Response.ContentType = "text/html"
+	c.RenderTextWithStatus(http.StatusTeapot, "No coffee, %d cups of tea", 2).Apply(c.Request, c.Response)
+
+	eq(t, "status", resp.Code, http.StatusTeapot)
+	eq(t, "content type", resp.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	eq(t, "body", resp.Body.String(), "No coffee, 2 cups of tea")
+}
+
+// Test that JSON is rendered with the given status.
+func TestRenderJSONWithStatus(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.Response.ContentType = "text/html"
+	c.RenderJSONWithStatus(http.StatusUnprocessableEntity, map[string]string{"error": "invalid name"}).Apply(c.Request, c.Response)
+
+	eq(t, "status", resp.Code, http.StatusUnprocessableEntity)
+	eq(t, "content type", resp.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	eq(t, "body", resp.Body.String(), `{"error":"invalid name"}`)
+}
+
+// Test that RenderNegotiated encodes with a registered codec the request
+// accepts, and with JSON otherwise.
+func TestRenderNegotiatedCodec(t *testing.T) {
+	startFakeBookingApp()
+	defer func(saved []codec) { codecs = saved }(codecs)
+	RegisterCodec("application/x-msgpack", func(w io.Writer, o interface{}) error {
+		_, err := fmt.Fprintf(w, "msgpack:%v", o)
+		return err
+	})
+
+	render := func(accept string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/hotels/3", nil)
+		r.Header.Set("Accept", accept)
+		c := NewController(NewRequest(r), NewResponse(resp))
+		c.RenderNegotiated(map[string]int{"id": 3}).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := render("application/x-msgpack")
+	eq(t, "codec status", resp.Code, http.StatusOK)
+	eq(t, "codec content type", resp.Header().Get("Content-Type"), "application/x-msgpack")
+	eq(t, "codec body", resp.Body.String(), "msgpack:map[id:3]")
+
+	resp = render("application/json")
+	eq(t, "json content type", resp.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	eq(t, "json body", resp.Body.String(), `{"id":3}`)
+}
+
+type failingJSON struct{}
+
+func (failingJSON) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("cannot marshal")
+}
+
+// Test that a failure to marshal the JSON results in a 500 error page.
+func TestRenderJSONMarshalError(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	req := NewRequest(httptest.NewRequest("GET", "/", nil))
+	req.Format = "json"
+	c := NewController(req, NewResponse(resp))
+	c.RenderJSONWithStatus(http.StatusCreated, []interface{}{"partial", failingJSON{}}).Apply(c.Request, c.Response)
+
+	eq(t, "status", resp.Code, http.StatusInternalServerError)
+	eq(t, "content type", resp.Header().Get("Content-Type"), "application/json")
+	if body := resp.Body.String(); strings.Contains(body, "partial") {
+		t.Errorf("Expected no partial output, got %s", body)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Errorf("Expected a JSON error body, got %s (%s)", resp.Body, err)
+	}
+}
+
+// Test that a partial is rendered without the surrounding page.
+func TestRenderPartial(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.ViewArgs["hotel"] = &Hotel{3, "A Hotel", "300 Main St.", "New York", "NY", "10010", "USA", 300}
+	c.RenderPartial("hotels/partial.html", "hotels/partial/details").Apply(c.Request, c.Response)
+
+	eq(t, "status", resp.Code, http.StatusOK)
+	body := resp.Body.String()
+	if !strings.Contains(body, `<div class="hotel">`) || !strings.Contains(body, "A Hotel") {
+		t.Errorf("Expected the partial in the response:\n%s", body)
+	}
+	if strings.Contains(body, "<html>") || strings.Contains(body, `id="footer"`) {
+		t.Errorf("Found the page layout in the partial response:\n%s", body)
+	}
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderPartial("hotels/partial.html", "hotels/partial/missing").Apply(c.Request, c.Response)
+	eq(t, "missing partial status", resp.Code, http.StatusInternalServerError)
+}
+
+// Test that a flush sends the data written so far before the action returns.
+func TestResponseFlush(t *testing.T) {
+	startFakeBookingApp()
+	flushed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := NewResponse(w)
+		resp.WriteHeader(http.StatusOK, "text/plain")
+		if _, err := resp.Out.Write([]byte("first\n")); err != nil {
+			t.Errorf("Write failed: %s", err)
+		}
+		resp.Flush()
+		select {
+		case <-flushed:
+		case <-time.After(5 * time.Second):
+		}
+		if _, err := resp.Out.Write([]byte("second\n")); err != nil {
+			t.Errorf("Write failed: %s", err)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+	line, err := body.ReadString('\n')
+	if err != nil || line != "first\n" {
+		t.Errorf("Expected the flushed line, got %q (%v)", line, err)
+	}
+	close(flushed)
+	if line, _ = body.ReadString('\n'); line != "second\n" {
+		t.Errorf("Expected the second line, got %q", line)
+	}
+}
+
+// Test that a download to a client which stopped reading is aborted.
+func TestBinaryResultWriteTimeout(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.write.timeout", "1")
+	done := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		c := NewController(NewRequest(r), NewResponse(w))
+		c.RenderBinary(bytes.NewReader(make([]byte, 64<<20)), "large.bin", Attachment, time.Now()).Apply(c.Request, c.Response)
+	}))
+	server.Config.ConnState = trackConn
+	server.Start()
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err = fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Never read the response
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("The stalled download was not aborted")
+	}
+}
+
+// deadlineConn records the write deadlines set on it.
+type deadlineConn struct {
+	net.Conn
+	deadline time.Time
+}
+
+func (c *deadlineConn) SetWriteDeadline(t time.Time) error {
+	c.deadline = t
+	return nil
+}
+
+// Test that the write deadline of the server is restored after the result,
+// and that flushing passes through the deadline writer.
+func TestWriteDeadlineRestore(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("results.write.timeout", "1")
+	defer Config.SetOption("results.write.timeout", "0")
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), http.ServerContextKey, &http.Server{WriteTimeout: 30 * time.Second}))
+	conn := &deadlineConn{}
+	serverConnsLock.Lock()
+	serverConns[r.RemoteAddr] = conn
+	serverConnsLock.Unlock()
+	defer func() {
+		serverConnsLock.Lock()
+		delete(serverConns, r.RemoteAddr)
+		serverConnsLock.Unlock()
+	}()
+
+	recorder := httptest.NewRecorder()
+	resp := NewResponse(recorder)
+	restore := withWriteDeadline(NewRequest(r), resp)
+	flusher, ok := resp.Out.(http.Flusher)
+	if !ok {
+		t.Fatal("Expected the deadline writer to flush")
+	}
+	flusher.Flush()
+	eq(t, "flushed", recorder.Flushed, true)
+
+	restore()
+	if remaining := time.Until(conn.deadline); remaining < 29*time.Second || remaining > 30*time.Second {
+		t.Errorf("Expected the deadline of the server's write timeout, got %s", remaining)
+	}
+}
+
+// Test that the stack trace is only disclosed in dev mode or when configured.
+func TestErrorResultDisclose(t *testing.T) {
+	startFakeBookingApp()
+	render := func(format string) string {
+		resp := httptest.NewRecorder()
+		req := NewRequest(httptest.NewRequest("GET", "/", nil))
+		req.Format = format
+		result := ErrorResult{Error: &Error{
+			Title:       "Runtime Panic",
+			Description: "boom",
+			Stack:       "the-stack-trace",
+		}}
+		result.Apply(req, NewResponse(resp))
+		eq(t, format+" status", http.StatusInternalServerError, resp.Code)
+		return resp.Body.String()
+	}
+
+	for _, format := range []string{"html", "json", "txt"} {
+		if body := render(format); strings.Contains(body, "the-stack-trace") {
+			t.Errorf("Expected no stack in prod mode for %s, got %s", format, body)
+		}
+	}
+
+	DevMode = true
+	for _, format := range []string{"html", "json", "txt"} {
+		if body := render(format); !strings.Contains(body, "the-stack-trace") {
+			t.Errorf("Expected the stack in dev mode for %s, got %s", format, body)
+		}
+	}
+
+	Config.SetOverride("errors.disclose", "false")
+	if body := render("html"); strings.Contains(body, "the-stack-trace") {
+		t.Errorf("Expected no stack with errors.disclose=false, got %s", body)
+	}
+	Config.RemoveOverride("errors.disclose")
+	DevMode = false
+
+	Config.SetOverride("errors.disclose", "true")
+	defer Config.RemoveOverride("errors.disclose")
+	if body := render("json"); !strings.Contains(body, "the-stack-trace") {
+		t.Errorf("Expected the stack with errors.disclose=true, got %s", body)
+	}
+}
+
 func BenchmarkRenderChunked(b *testing.B) {
 	startFakeBookingApp()
 	resp := httptest.NewRecorder()
@@ -58,3 +374,141 @@ func BenchmarkRenderNotChunked(b *testing.B) {
 		hotels.Show(3).Apply(c.Request, c.Response)
 	}
 }
+
+// Test that every built-in result sends a content type.
+func TestResultContentTypes(t *testing.T) {
+	startFakeBookingApp()
+	results := map[string]func(c *Controller) Result{
+		"template": func(c *Controller) Result { return c.RenderTemplate("hotels/confirmation.txt") },
+		"json":     func(c *Controller) Result { return c.RenderJSON("ok") },
+		"jsonp":    func(c *Controller) Result { return c.RenderJSONP("cb", "ok") },
+		"xml":      func(c *Controller) Result { return c.RenderXML(&Hotel{Name: "A Hotel"}) },
+		"text":     func(c *Controller) Result { return c.RenderText("ok") },
+		"html":     func(c *Controller) Result { return c.RenderHTML("<p>ok</p>") },
+		"binary": func(c *Controller) Result {
+			return c.RenderBinary(strings.NewReader("ok"), "data", Attachment, time.Now())
+		},
+		"error":     func(c *Controller) Result { return c.NotFound("missing") },
+		"redirect":  func(c *Controller) Result { return c.Redirect("/hotels") },
+		"plaintext": func(c *Controller) Result { return PlaintextErrorResult{fmt.Errorf("failed")} },
+	}
+	for name, result := range results {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		result(c).Apply(c.Request, c.Response)
+		if resp.Header().Get("Content-Type") == "" {
+			t.Errorf("%s result sent no content type", name)
+		}
+	}
+}
+
+func TestNoSniff(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/hotels", nil))
+	eq(t, "disabled", resp.Header().Get("X-Content-Type-Options"), "")
+
+	Config.SetOption("response.nosniff", "true")
+	defer Config.SetOption("response.nosniff", "false")
+	resp = httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/hotels", nil))
+	eq(t, "enabled", resp.Header().Get("X-Content-Type-Options"), "nosniff")
+	if resp.Header().Get("Content-Type") == "" {
+		t.Error("Expected a content type")
+	}
+}
+
+func TestRenderAttachment(t *testing.T) {
+	startFakeBookingApp()
+	render := func(name string, reader io.Reader) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		c.RenderAttachment(name, reader).Apply(c.Request, c.Response)
+		return resp
+	}
+
+	resp := render("report.csv", strings.NewReader("a,b\n1,2\n"))
+	eq(t, "disposition", resp.Header().Get("Content-Disposition"), `attachment; filename="report.csv"`)
+	eq(t, "content type", resp.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+	eq(t, "body", resp.Body.String(), "a,b\n1,2\n")
+
+	// A stream without seeking
+	resp = render(`Überblick "2017".txt`, ioutil.NopCloser(strings.NewReader("streamed")))
+	eq(t, "utf-8 disposition", resp.Header().Get("Content-Disposition"),
+		`attachment; filename="_berblick \"2017\".txt"; filename*=UTF-8''%C3%9Cberblick%20%222017%22.txt`)
+	eq(t, "streamed body", resp.Body.String(), "streamed")
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestRenderReader(t *testing.T) {
+	startFakeBookingApp()
+	reader := &closeRecorder{Reader: strings.NewReader(`{"proxied":true}`)}
+	filters := Filters
+	defer func() { Filters = filters }()
+	Filters = []Filter{CompressFilter, func(c *Controller, fc []Filter) {
+		c.Result = c.RenderReader("application/json", reader)
+	}}
+
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/hotels", nil))
+	eq(t, "status", resp.Code, http.StatusOK)
+	eq(t, "content type", resp.Header().Get("Content-Type"), "application/json")
+	eq(t, "body", resp.Body.String(), `{"proxied":true}`)
+	eq(t, "closed", reader.closed, true)
+}
+
+// Test that the error page shows the configured context and stack depth.
+func TestErrorContextAndStackDepth(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOverride("errors.disclose", "true")
+	Config.SetOption("errors.context.lines", "2")
+	Config.SetOption("errors.stack.depth", "2")
+	defer func() {
+		Config.RemoveOverride("errors.disclose")
+		Config.SetOption("errors.context.lines", "5")
+		Config.SetOption("errors.stack.depth", "0")
+	}()
+
+	source := make([]string, 30)
+	for i := range source {
+		source[i] = fmt.Sprintf("source line %d", i+1)
+	}
+	stack := "/app/controllers/app.go:15 +0x1\n" +
+		"app.Caller(...)\n\t/app/controllers/caller.go:7 +0x2\n" +
+		"app.Outer(...)\n\t/app/controllers/outer.go:3 +0x3\n"
+	resp := httptest.NewRecorder()
+	req := NewRequest(httptest.NewRequest("GET", "/", nil))
+	req.Format = "html"
+	ErrorResult{Error: &Error{
+		Title:       "Runtime Panic",
+		Path:        "app/controllers/app.go",
+		Line:        15,
+		SourceLines: source,
+		Stack:       limitStack(stack),
+	}}.Apply(req, NewResponse(resp))
+
+	body := resp.Body.String()
+	eq(t, "context lines", strings.Count(body, `class="lineNumber"`), 5)
+	for _, line := range []string{"source line 13", "source line 17"} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %s in the context", line)
+		}
+	}
+	for _, line := range []string{"source line 12<", "source line 18"} {
+		if strings.Contains(body, line) {
+			t.Errorf("Expected no %s in the context", line)
+		}
+	}
+	if !strings.Contains(body, "caller.go:7") || strings.Contains(body, "outer.go") {
+		t.Errorf("Expected a stack of 2 frames, got %s", body)
+	}
+}
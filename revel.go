@@ -60,7 +60,7 @@ var (
 	ImportPath string // e.g. "corp/sample"
 	SourcePath string // e.g. "$GOPATH/src"
 
-	Config  *config.Context
+	Config  *ConfigContext
 	RunMode string // Application-defined (by default, "dev" or "prod")
 	DevMode bool   // if true, RunMode is a development mode.
 
@@ -186,11 +186,11 @@ func Init(mode, importPath, srcPath string) {
 	}
 
 	// Load app.conf
-	var err error
-	Config, err = config.LoadContext("app.conf", ConfPaths)
-	if err != nil || Config == nil {
+	context, err := config.LoadContext("app.conf", ConfPaths)
+	if err != nil || context == nil {
 		log.Fatalln("Failed to load app.conf:", err)
 	}
+	Config = NewConfigContext(context)
 	// Ensure that the selected runmode appears in app.conf.
 	// If empty string is passed as the mode, treat it as "DEFAULT"
 	if mode == "" {
@@ -232,6 +232,9 @@ func Init(mode, importPath, srcPath string) {
 		gocolorize.SetPlain(true)
 	}
 
+	// Write the records still queued by the loggers being replaced
+	closeAsyncLogs()
+
 	TRACE = getLogger("trace")
 	INFO = getLogger("info")
 	WARN = getLogger("warn")
@@ -303,6 +306,12 @@ func getLogger(name string) *log.Logger {
 		logger = newLogger(file)
 	}
 
+	// Keep the writes off the logging goroutine if desired. The error log
+	// stays synchronous, so the record of a Fatal call isn't lost on exit.
+	if !strings.EqualFold(name, "error") && Config.BoolDefault("log.async", false) {
+		logger.SetOutput(newAsyncLogWriter(logger.Writer()))
+	}
+
 	if strings.EqualFold(name, "request") {
 		logger.SetFlags(0)
 		return logger
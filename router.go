@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -23,6 +24,14 @@ const (
 	httpStatusCode = "404"
 )
 
+// Trailing slash handling modes, selected with the "router.trailingslash"
+// config option.
+const (
+	TrailingSlashIgnore = "ignore" // /users and /users/ route to the same action (default)
+	TrailingSlashStrip  = "strip"  // /users/ is permanently redirected to /users
+	TrailingSlashAppend = "append" // /users is permanently redirected to /users/
+)
+
 type Route struct {
 	ModuleSource        *Module         // Module name of route
 	Method              string          // e.g. GET
@@ -46,6 +55,8 @@ type RouteMatch struct {
 	FixedParams      []string
 	Params           map[string][]string // e.g. {id: 123}
 	TypeOfController *ControllerType     // The controller type
+	CanonicalPath    string              // e.g. /app/123 when /App/123 was matched case insensitively
+	Path             string              // e.g. /app/:id
 }
 
 type ActionPathData struct {
@@ -67,8 +78,26 @@ var (
 	actionPathCacheLock = sync.Mutex{}
 	// The path returned if not found
 	notFound = &RouteMatch{Action: "404"}
+
+	// Functions invoked after the routes file has been (re)loaded
+	routesLoadedHooks []func(*Router)
 )
 
+// OnRoutesLoaded registers a function which is invoked whenever the routes
+// file has been loaded, including reloads by the watcher. It may add routes
+// to the router, e.g. with AddRoute, before they are made matchable.
+func OnRoutesLoaded(f func(*Router)) {
+	routesLoadedHooks = append(routesLoadedHooks, f)
+}
+
+// AddRoute adds a route of the app, as if it were a line of the routes
+// file, e.g. router.AddRoute("GET", "/admin/users", "Admin.Users"). Routes
+// added after the router has been refreshed only match after the next
+// refresh, so add them from an OnRoutesLoaded function.
+func (router *Router) AddRoute(method, path, action string) {
+	router.Routes = append(router.Routes, NewRoute(appModule, strings.ToUpper(method), path, action, "", "", 0))
+}
+
 func init() {
 	AddInitEventHandler(func(typeOf int, value interface{}) (responseOf int) {
 		// Add in an
@@ -155,10 +184,11 @@ func treePath(method, path string) string {
 }
 
 type Router struct {
-	Routes []*Route
-	Tree   *pathtree.Node
-	Module string // The module the route is associated with
-	path   string // path to the routes file
+	Routes          []*Route
+	Tree            *pathtree.Node
+	Module          string // The module the route is associated with
+	CaseInsensitive bool   // Match the static parts of route paths regardless of case
+	path            string // path to the routes file
 }
 
 func (router *Router) Route(req *http.Request) (routeMatch *RouteMatch) {
@@ -167,11 +197,26 @@ func (router *Router) Route(req *http.Request) (routeMatch *RouteMatch) {
 		req.Method = method
 	}
 
-	leaf, expansions := router.Tree.Find(treePath(req.Method, req.URL.Path))
+	path := req.URL.Path
+	if router.CaseInsensitive {
+		path = strings.ToLower(path)
+	}
+	leaf, expansions := router.Tree.Find(treePath(req.Method, path))
 	if leaf == nil {
 		return nil
 	}
 
+	// The lowercased path was matched, take the wildcard values from the
+	// original path so captured parameters keep their case.
+	var canonicalPath string
+	if router.CaseInsensitive {
+		matched := leaf.Value.([]*Route)[0]
+		expansions, canonicalPath = caseInsensitiveMatch(matched, req.Method, req.URL.Path)
+		if canonicalPath == req.URL.Path {
+			canonicalPath = ""
+		}
+	}
+
 	// Create a map of the route parameters.
 	var params url.Values
 	if len(expansions) > 0 {
@@ -227,12 +272,100 @@ func (router *Router) Route(req *http.Request) (routeMatch *RouteMatch) {
 			Params:           params,
 			FixedParams:      route.FixedParams,
 			TypeOfController: typeOfController,
+			CanonicalPath:    canonicalPath,
+			Path:             route.Path,
 		}
 	}
 
 	return
 }
 
+// routeMethods are the methods AllowedMethods looks for.
+var routeMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// AllowedMethods returns the methods of the routes matching the path, for the
+// Allow header of 405 and OPTIONS responses.
+func (router *Router) AllowedMethods(path string) (methods []string) {
+	if router.CaseInsensitive {
+		path = strings.ToLower(path)
+	}
+	for _, method := range routeMethods {
+		leaf, _ := router.Tree.Find(treePath(method, path))
+		if leaf == nil {
+			continue
+		}
+		if routes := leaf.Value.([]*Route); len(routes) > 0 && routes[0].Action != httpStatusCode {
+			methods = append(methods, method)
+		}
+	}
+	return
+}
+
+// caseInsensitiveMatch maps a route matched against the lowercased request
+// path back onto the original path. It returns the wildcard expansions taken
+// from the original path and the canonical path, which spells the static
+// segments the way the route does.
+func caseInsensitiveMatch(route *Route, method, path string) (expansions []string, canonicalPath string) {
+	if route.Method == "*" {
+		expansions = append(expansions, method)
+	}
+
+	var (
+		patternSegments = splitPathSegments(route.Path)
+		pathSegments    = splitPathSegments(path)
+		canonical       = make([]string, 0, len(pathSegments))
+	)
+	for i, segment := range patternSegments {
+		if i >= len(pathSegments) {
+			break
+		}
+		if strings.HasPrefix(segment, "*") {
+			// A star consumes the rest of the path
+			rest := strings.Join(pathSegments[i:], "/")
+			expansions = append(expansions, rest)
+			canonical = append(canonical, rest)
+			break
+		}
+		if strings.HasPrefix(segment, ":") {
+			expansions = append(expansions, pathSegments[i])
+			canonical = append(canonical, pathSegments[i])
+		} else {
+			canonical = append(canonical, segment)
+		}
+	}
+
+	canonicalPath = "/" + strings.Join(canonical, "/")
+	if len(canonical) > 0 && strings.HasSuffix(path, "/") {
+		canonicalPath += "/"
+	}
+	return
+}
+
+// splitPathSegments splits a path on "/" ignoring the leading and trailing
+// slash, the same way the path tree does.
+func splitPathSegments(path string) []string {
+	segments := strings.Split(path, "/")
+	if len(segments) > 0 && segments[0] == "" {
+		segments = segments[1:]
+	}
+	if len(segments) > 0 && segments[len(segments)-1] == "" {
+		segments = segments[:len(segments)-1]
+	}
+	return segments
+}
+
+// lowerStaticSegments lowercases the static segments of a route path, leaving
+// the names of :wildcard and *wildcard segments untouched.
+func lowerStaticSegments(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment != "" && segment[0] != ':' && segment[0] != '*' {
+			segments[i] = strings.ToLower(segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
 // Refresh re-reads the routes file and re-calculates the routing table.
 // Returns an error if a specified action could not be found.
 func (router *Router) Refresh() (err *Error) {
@@ -242,6 +375,12 @@ func (router *Router) Refresh() (err *Error) {
 	if err != nil {
 		return
 	}
+	for _, f := range routesLoadedHooks {
+		f(router)
+	}
+	if err = checkDuplicateRoutes(router.Routes); err != nil {
+		return
+	}
 	err = router.updateTree()
 	return
 }
@@ -255,11 +394,15 @@ func (router *Router) updateTree() *Error {
 	// based on wildcard matches,
 	// TODO when pathtree is fixed (made to be smart enough to not require a predefined intake order) keeping the routes in order is not necessary
 	for _, route := range router.Routes {
-		if _, found := pathMap[route.TreePath]; !found {
-			pathMap[route.TreePath] = append(pathMap[route.TreePath], route)
-			allPathsOrdered = append(allPathsOrdered, route.TreePath)
+		routeTreePath := route.TreePath
+		if router.CaseInsensitive {
+			routeTreePath = treePath(route.Method, lowerStaticSegments(route.Path))
+		}
+		if _, found := pathMap[routeTreePath]; !found {
+			pathMap[routeTreePath] = append(pathMap[routeTreePath], route)
+			allPathsOrdered = append(allPathsOrdered, routeTreePath)
 		} else {
-			pathMap[route.TreePath] = append(pathMap[route.TreePath], route)
+			pathMap[routeTreePath] = append(pathMap[routeTreePath], route)
 		}
 	}
 	for _, path := range allPathsOrdered {
@@ -268,7 +411,7 @@ func (router *Router) updateTree() *Error {
 
 		// Allow GETs to respond to HEAD requests.
 		if err == nil && routeList[0].Method == "GET" {
-			err = router.Tree.Add(treePath("HEAD", routeList[0].Path), routeList)
+			err = router.Tree.Add("/HEAD"+path[len("/GET"):], routeList)
 		}
 
 		// Error adding a route to the pathtree.
@@ -404,7 +547,8 @@ func splitActionPath(actionPathData *ActionPathData, actionPath string, useCache
 }
 
 // parseRoutesFile reads the given routes file and returns the contained routes.
-func parseRoutesFile(moduleSource *Module, routesPath, joinedPath string, validate bool) ([]*Route, *Error) {
+// The including files are the chain of routes files including it, if any.
+func parseRoutesFile(moduleSource *Module, routesPath, joinedPath string, validate bool, including ...string) ([]*Route, *Error) {
 	contentBytes, err := ioutil.ReadFile(routesPath)
 	if err != nil {
 		return nil, &Error{
@@ -412,11 +556,11 @@ func parseRoutesFile(moduleSource *Module, routesPath, joinedPath string, valida
 			Description: err.Error(),
 		}
 	}
-	return parseRoutes(moduleSource, routesPath, joinedPath, string(contentBytes), validate)
+	return parseRoutes(moduleSource, routesPath, joinedPath, string(contentBytes), validate, including...)
 }
 
 // parseRoutes reads the content of a routes file into the routing table.
-func parseRoutes(moduleSource *Module, routesPath, joinedPath, content string, validate bool) ([]*Route, *Error) {
+func parseRoutes(moduleSource *Module, routesPath, joinedPath, content string, validate bool, including ...string) ([]*Route, *Error) {
 	var routes []*Route
 
 	// For each line..
@@ -426,7 +570,10 @@ func parseRoutes(moduleSource *Module, routesPath, joinedPath, content string, v
 			continue
 		}
 
-		const modulePrefix = "module:"
+		const (
+			modulePrefix  = "module:"
+			includePrefix = "include:"
+		)
 
 		// Handle included routes from modules.
 		// e.g. "module:testrunner" imports all routes from that module.
@@ -439,6 +586,17 @@ func parseRoutes(moduleSource *Module, routesPath, joinedPath, content string, v
 			continue
 		}
 
+		// Handle included routes files, relative to the including one.
+		// e.g. "include:admin.routes" imports all routes from conf/admin.routes.
+		if strings.HasPrefix(line, includePrefix) {
+			includedRoutes, err := getIncludedRoutes(moduleSource, routesPath, line[len(includePrefix):], joinedPath, validate, including)
+			if err != nil {
+				return nil, routeError(err, routesPath, content, n)
+			}
+			routes = append(routes, includedRoutes...)
+			continue
+		}
+
 		// A single route
 		method, path, action, fixedArgs, found := parseRouteLine(line)
 		if !found {
@@ -466,6 +624,16 @@ func parseRoutes(moduleSource *Module, routesPath, joinedPath, content string, v
 			continue
 		}
 
+		// Likewise for included routes files, e.g. "* /admin include:admin.routes"
+		if method == "*" && strings.HasPrefix(action, includePrefix) {
+			includedRoutes, err := getIncludedRoutes(moduleSource, routesPath, action[len(includePrefix):], path, validate, including)
+			if err != nil {
+				return nil, routeError(err, routesPath, content, n)
+			}
+			routes = append(routes, includedRoutes...)
+			continue
+		}
+
 		route := NewRoute(moduleSource, method, path, action, fixedArgs, routesPath, n)
 		routes = append(routes, route)
 
@@ -551,6 +719,41 @@ func getModuleRoutes(moduleName, joinedPath string, validate bool) (routes []*Ro
 	return routes, err
 }
 
+// getIncludedRoutes loads the routes file included by the given routes file
+// and returns the list of routes.
+func getIncludedRoutes(moduleSource *Module, routesPath, includePath, joinedPath string, validate bool, including []string) ([]*Route, *Error) {
+	if !filepath.IsAbs(includePath) {
+		includePath = filepath.Join(filepath.Dir(routesPath), includePath)
+	}
+	including = append(including, routesPath)
+	for _, path := range including {
+		if path == includePath {
+			return nil, &Error{
+				Title:       "Route validation error",
+				Description: "Routes include cycle: " + strings.Join(append(including, includePath), " -> "),
+			}
+		}
+	}
+	return parseRoutesFile(moduleSource, includePath, joinedPath, validate, including...)
+}
+
+// checkDuplicateRoutes returns an error if the same method and path are
+// routed by more than one routes file of a module, e.g. an included one.
+// The routes of another module may be overridden, the first route wins.
+func checkDuplicateRoutes(routes []*Route) *Error {
+	defined := map[string]*Route{}
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if previous, found := defined[key]; !found {
+			defined[key] = route
+		} else if previous.ModuleSource == route.ModuleSource && previous.routesPath != route.routesPath {
+			return routeError(fmt.Errorf("Duplicate route %s, already defined in %s:%d",
+				key, previous.routesPath, previous.line+1), route.routesPath, "", route.line)
+		}
+	}
+	return nil
+}
+
 // Groups:
 // 1: method
 // 4: path
@@ -672,7 +875,15 @@ func (router *Router) Reverse(action string, argValues map[string]string) (ad *A
 					val = "<nil>"
 					ERROR.Print("revel/router: reverse route missing route arg ", el[1:])
 				}
-				pathElements[i] = val
+				// Escape the value, a catch all may span several path segments
+				segments := strings.Split(val, "/")
+				if el[0] == ':' {
+					segments = []string{val}
+				}
+				for j, segment := range segments {
+					segments[j] = url.PathEscape(segment)
+				}
+				pathElements[i] = strings.Join(segments, "/")
 				delete(argValues, el[1:])
 				continue
 			}
@@ -711,10 +922,89 @@ func (router *Router) Reverse(action string, argValues map[string]string) (ad *A
 	return nil
 }
 
+// trailingSlashRedirect returns the canonical URL the request should be
+// redirected to according to the "router.trailingslash" option, or an empty
+// string if no redirect is necessary. Only GET and HEAD requests are
+// redirected, other methods are routed as if the slash was not there.
+func trailingSlashRedirect(req *http.Request) string {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return ""
+	}
+	path := req.URL.Path
+	if path == "" || path == "/" {
+		return ""
+	}
+
+	switch Config.StringDefault("router.trailingslash", TrailingSlashIgnore) {
+	case TrailingSlashStrip:
+		if !strings.HasSuffix(path, "/") {
+			return ""
+		}
+		path = strings.TrimRight(path, "/")
+		if path == "" {
+			path = "/"
+		}
+	case TrailingSlashAppend:
+		if strings.HasSuffix(path, "/") {
+			return ""
+		}
+		path += "/"
+	default:
+		return ""
+	}
+
+	redirect := url.URL{Path: path, RawQuery: req.URL.RawQuery}
+	return redirect.String()
+}
+
+// spaFallback returns the path of the single-page app index file to serve
+// instead of a 404 for an unmatched request, if configured by
+// router.spa.fallback. Only GET requests accepting HTML qualify, and paths
+// below router.spa.apiprefix (/api/ by default) keep returning a 404.
+func spaFallback(req *http.Request) string {
+	index := Config.StringDefault("router.spa.fallback", "")
+	if index == "" || (req.Method != "GET" && req.Method != "HEAD") ||
+		!strings.Contains(req.Header.Get("Accept"), "text/html") ||
+		strings.HasPrefix(req.URL.Path, Config.StringDefault("router.spa.apiprefix", "/api/")) {
+		return ""
+	}
+	if !filepath.IsAbs(index) {
+		index = filepath.Join(BasePath, index)
+	}
+	return index
+}
+
 func RouterFilter(c *Controller, fc []Filter) {
+	// Normalize the trailing slash if configured to do so
+	if redirect := trailingSlashRedirect(c.Request.Request); redirect != "" {
+		c.Response.Status = http.StatusMovedPermanently
+		c.Result = &RedirectToURLResult{redirect}
+		return
+	}
+
 	// Figure out the Controller/Action
 	route := MainRouter.Route(c.Request.Request)
+
+	// Answer OPTIONS requests for the paths routed if configured to do so,
+	// unless there is an OPTIONS route
+	if (route == nil || route.Action == httpStatusCode) && c.Request.Method == "OPTIONS" &&
+		Config.BoolDefault("router.autooptions", false) {
+		if methods := MainRouter.AllowedMethods(c.Request.URL.Path); len(methods) > 0 {
+			c.Response.Out.Header().Set("Allow", strings.Join(append(methods, "OPTIONS"), ", "))
+			c.Result = c.NoContent()
+			return
+		}
+	}
+
 	if route == nil {
+		// Let a single-page app handle the path on the client if configured
+		if index := spaFallback(c.Request.Request); index != "" {
+			if file, err := os.Open(index); err == nil {
+				c.Result = c.RenderFile(file, Inline)
+				return
+			}
+			WARN.Println("Failed to open router.spa.fallback:", index)
+		}
 		c.Result = c.NotFound("No matching route found: " + c.Request.RequestURI)
 		return
 	}
@@ -725,6 +1015,15 @@ func RouterFilter(c *Controller, fc []Filter) {
 		return
 	}
 
+	// Permanently redirect to the canonical casing of the path if desired.
+	if route.CanonicalPath != "" && (c.Request.Method == "GET" || c.Request.Method == "HEAD") &&
+		Config.BoolDefault("router.caseinsensitive.redirect", false) {
+		redirect := url.URL{Path: route.CanonicalPath, RawQuery: c.Request.URL.RawQuery}
+		c.Response.Status = http.StatusMovedPermanently
+		c.Result = &RedirectToURLResult{redirect.String()}
+		return
+	}
+
 	// Set the action.
 	if err := c.SetTypeAction(route.ControllerName, route.MethodName, route.TypeOfController); err != nil {
 		c.Result = c.NotFound(err.Error())
@@ -732,6 +1031,7 @@ func RouterFilter(c *Controller, fc []Filter) {
 	}
 
 	// Add the route and fixed params to the Request Params.
+	c.Request.RoutePath = route.Path
 	c.Params.Route = route.Params
 
 	// Add the fixed parameters mapped by name.
@@ -792,6 +1092,7 @@ func HTTPMethodOverride(c *Controller, fc []Filter) {
 func init() {
 	OnAppStart(func() {
 		MainRouter = NewRouter(filepath.Join(BasePath, "conf", "routes"))
+		MainRouter.CaseInsensitive = Config.BoolDefault("router.caseinsensitive", false)
 		err := MainRouter.Refresh()
 		if MainWatcher != nil && Config.BoolDefault("watch.routes", true) {
 			MainWatcher.Listen(MainRouter, MainRouter.path)
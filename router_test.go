@@ -6,8 +6,12 @@ package revel
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -471,6 +475,321 @@ func TestOverrideMethodFilter(t *testing.T) {
 	}
 }
 
+func TestTrailingSlashRedirect(t *testing.T) {
+	startFakeBookingApp()
+	defer Config.SetOption("router.trailingslash", TrailingSlashIgnore)
+
+	testCases := []struct {
+		mode, path, location string
+	}{
+		{TrailingSlashStrip, "/hotels/3/?page=2", "/hotels/3?page=2"},
+		{TrailingSlashStrip, "/hotels/3?page=2", ""},
+		{TrailingSlashAppend, "/hotels/3?page=2", "/hotels/3/?page=2"},
+		{TrailingSlashAppend, "/hotels/3/?page=2", ""},
+	}
+	for _, testCase := range testCases {
+		Config.SetOption("router.trailingslash", testCase.mode)
+		req, _ := http.NewRequest("GET", testCase.path, nil)
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(req), NewResponse(resp))
+		RouterFilter(c, NilChain)
+
+		if testCase.location == "" {
+			if c.Result != nil {
+				t.Errorf("%s %s: expected no redirect, got %#v", testCase.mode, testCase.path, c.Result)
+			}
+			continue
+		}
+		if c.Result == nil {
+			t.Errorf("%s %s: expected a redirect", testCase.mode, testCase.path)
+			continue
+		}
+		c.Result.Apply(c.Request, c.Response)
+		eq(t, "Status", resp.Code, http.StatusMovedPermanently)
+		eq(t, "Location", resp.Header().Get("Location"), testCase.location)
+	}
+}
+
+func TestTrailingSlashIgnore(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("router.trailingslash", TrailingSlashIgnore)
+
+	for _, path := range []string{"/hotels/3", "/hotels/3/"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+		RouterFilter(c, NilChain)
+		if c.Result != nil {
+			t.Errorf("%s: expected the route to match, got %#v", path, c.Result)
+			continue
+		}
+		eq(t, "Action", c.Action, "Hotels.Show")
+		eq(t, "Param id", c.Params.Route.Get("id"), "3")
+	}
+}
+
+func TestCaseInsensitiveRouteMatches(t *testing.T) {
+	initControllers()
+	router := NewRouter("")
+	router.Routes, _ = parseRoutes(appModule, "", "", TestRoutes, false)
+	if err := router.updateTree(); err != nil {
+		t.Errorf("updateTree failed: %s", err)
+	}
+	req := &http.Request{Method: "GET", URL: &url.URL{Path: "/Test/"}}
+	if route := router.Route(req); route != nil {
+		t.Errorf("Expected no match for %s without case insensitive routing, got %#v", req.URL.Path, route)
+	}
+
+	router.CaseInsensitive = true
+	if err := router.updateTree(); err != nil {
+		t.Errorf("updateTree failed: %s", err)
+	}
+
+	route := router.Route(req)
+	if route == nil {
+		t.Fatalf("Expected a match for %s", req.URL.Path)
+	}
+	eq(t, "MethodName", route.MethodName, "index")
+	eq(t, "CanonicalPath", route.CanonicalPath, "/test/")
+
+	req = &http.Request{Method: "GET", URL: &url.URL{Path: "/APP/AbC"}}
+	route = router.Route(req)
+	if route == nil {
+		t.Fatalf("Expected a match for %s", req.URL.Path)
+	}
+	eq(t, "MethodName", route.MethodName, "show")
+	eq(t, "Params id", route.Params["id"][0], "AbC")
+	eq(t, "CanonicalPath", route.CanonicalPath, "/app/AbC")
+
+	req = &http.Request{Method: "GET", URL: &url.URL{Path: "/Public/CSS/Style.css"}}
+	route = router.Route(req)
+	if route == nil {
+		t.Fatalf("Expected a match for %s", req.URL.Path)
+	}
+	eq(t, "Params filepath", route.Params["filepath"][0], "CSS/Style.css")
+	eq(t, "CanonicalPath", route.CanonicalPath, "/public/CSS/Style.css")
+
+	req = &http.Request{Method: "GET", URL: &url.URL{Path: "/app/123"}}
+	route = router.Route(req)
+	if route == nil {
+		t.Fatalf("Expected a match for %s", req.URL.Path)
+	}
+	eq(t, "CanonicalPath", route.CanonicalPath, "")
+
+	actual := router.Reverse("Application.Show", map[string]string{"id": "AbC"})
+	eq(t, "Reverse URL", actual.URL, "/app/AbC/")
+}
+
+func TestCaseInsensitiveRedirect(t *testing.T) {
+	startFakeBookingApp()
+	MainRouter.CaseInsensitive = true
+	if err := MainRouter.updateTree(); err != nil {
+		t.Errorf("updateTree failed: %s", err)
+	}
+	defer Config.SetOption("router.caseinsensitive.redirect", "false")
+
+	req, _ := http.NewRequest("GET", "/Hotels/3?page=2", nil)
+	c := NewController(NewRequest(req), NewResponse(httptest.NewRecorder()))
+	RouterFilter(c, NilChain)
+	if c.Result != nil {
+		t.Errorf("Expected the route to match, got %#v", c.Result)
+	}
+	eq(t, "Action", c.Action, "Hotels.Show")
+
+	Config.SetOption("router.caseinsensitive.redirect", "true")
+	resp := httptest.NewRecorder()
+	c = NewController(NewRequest(req), NewResponse(resp))
+	RouterFilter(c, NilChain)
+	if c.Result == nil {
+		t.Fatal("Expected a redirect")
+	}
+	c.Result.Apply(c.Request, c.Response)
+	eq(t, "Status", resp.Code, http.StatusMovedPermanently)
+	eq(t, "Location", resp.Header().Get("Location"), "/hotels/3?page=2")
+}
+
+func TestSPAFallback(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("router.spa.fallback", "public/index.html")
+
+	htmlRequest := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		resp := httptest.NewRecorder()
+		handle(resp, req)
+		return resp
+	}
+
+	resp := htmlRequest("/app/users/5", "text/html,application/xhtml+xml")
+	eq(t, "spa status", http.StatusOK, resp.Code)
+	if !strings.Contains(resp.Body.String(), "Single page app") {
+		t.Errorf("Expected the spa index, got:\n%s", resp.Body)
+	}
+
+	resp = htmlRequest("/api/users/5", "text/html")
+	eq(t, "api status", http.StatusNotFound, resp.Code)
+
+	resp = htmlRequest("/app/users/5", "application/json")
+	eq(t, "json status", http.StatusNotFound, resp.Code)
+}
+
+func writeRoutesFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "revel-routes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIncludedRoutes(t *testing.T) {
+	startFakeBookingApp()
+	dir := writeRoutesFiles(t, map[string]string{
+		"routes": `
+GET     /hotels                  Hotels.Index
+include:hotels.routes
+*       /admin                   include:admin.routes
+`,
+		"hotels.routes": `GET /hotels/:id Hotels.Show`,
+		"admin.routes":  `GET /hotels/:id/booking Hotels.Book`,
+	})
+	defer os.RemoveAll(dir)
+
+	router := NewRouter(filepath.Join(dir, "routes"))
+	if err := router.Refresh(); err != nil {
+		t.Fatalf("Failed to load routes: %s", err)
+	}
+	var paths []string
+	for _, route := range router.Routes {
+		paths = append(paths, route.Method+" "+route.Path)
+	}
+	eq(t, "merged routes", "GET /hotels, GET /hotels/:id, GET /admin/hotels/:id/booking", strings.Join(paths, ", "))
+}
+
+func TestIncludedRoutesDuplicate(t *testing.T) {
+	startFakeBookingApp()
+	dir := writeRoutesFiles(t, map[string]string{
+		"routes":        "GET /hotels Hotels.Index\ninclude:hotels.routes\n",
+		"hotels.routes": "GET /hotels/:id Hotels.Show\nGET /hotels Hotels.Index\n",
+	})
+	defer os.RemoveAll(dir)
+
+	err := NewRouter(filepath.Join(dir, "routes")).Refresh()
+	if err == nil || !strings.Contains(err.Description, "Duplicate route GET /hotels") {
+		t.Errorf("Expected a duplicate route error, got %v", err)
+	}
+}
+
+// Test that the app's routes may override those of a module.
+func TestModuleRoutesOverridden(t *testing.T) {
+	startFakeBookingApp()
+	dir := writeRoutesFiles(t, map[string]string{
+		"routes": "GET /hotels Hotels.Show\nmodule:routesmodule\n*  /:controller/:action  :controller.:action\n",
+	})
+	defer os.RemoveAll(dir)
+	moduleDir := filepath.Join(dir, "routesmodule")
+	if err := os.MkdirAll(filepath.Join(moduleDir, "conf"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(moduleDir, "conf", "routes"),
+		[]byte("GET /hotels Hotels.Index\n*  /:controller/:action  :controller.:action\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(modules []*Module) { Modules = modules }(Modules)
+	Modules = append(Modules, &Module{Name: "routesmodule", Path: moduleDir})
+
+	router := NewRouter(filepath.Join(dir, "routes"))
+	if err := router.Refresh(); err != nil {
+		t.Fatalf("Failed to load routes: %s", err)
+	}
+	eq(t, "routes", len(router.Routes), 4)
+	match := router.Route(httptest.NewRequest("GET", "/hotels", nil))
+	eq(t, "overriding route", strings.ToLower(match.MethodName), "show")
+}
+
+func TestIncludedRoutesCycle(t *testing.T) {
+	startFakeBookingApp()
+	dir := writeRoutesFiles(t, map[string]string{
+		"routes":        "include:hotels.routes\n",
+		"hotels.routes": "GET /hotels Hotels.Index\ninclude:routes\n",
+	})
+	defer os.RemoveAll(dir)
+
+	err := NewRouter(filepath.Join(dir, "routes")).Refresh()
+	if err == nil || !strings.Contains(err.Description, "include cycle") {
+		t.Errorf("Expected an include cycle error, got %v", err)
+	}
+}
+
+func TestOnRoutesLoaded(t *testing.T) {
+	startFakeBookingApp()
+	hooks := routesLoadedHooks
+	defer func() { routesLoadedHooks = hooks }()
+	loads := 0
+	OnRoutesLoaded(func(router *Router) {
+		loads++
+		router.AddRoute("get", "/generated/:id", "Hotels.Show")
+	})
+
+	dir := writeRoutesFiles(t, map[string]string{"routes": "GET /hotels Hotels.Index\n"})
+	defer os.RemoveAll(dir)
+	router := NewRouter(filepath.Join(dir, "routes"))
+	for i := 0; i < 2; i++ {
+		if err := router.Refresh(); err != nil {
+			t.Fatalf("Failed to load routes: %s", err)
+		}
+		match := router.Route(httptest.NewRequest("GET", "/generated/3", nil))
+		if match == nil || match.Action == "404" {
+			t.Fatalf("Expected the added route to match, got %v", match)
+		}
+		eq(t, "added route method", match.MethodName, "show")
+		eq(t, "added route param", match.Params["id"][0], "3")
+	}
+	eq(t, "loads", loads, 2)
+	eq(t, "routes", len(router.Routes), 2)
+}
+
+func TestAutoOptions(t *testing.T) {
+	startFakeBookingApp()
+	dir := writeRoutesFiles(t, map[string]string{"routes": `
+GET     /hotels                  Hotels.Index
+POST    /hotels                  Hotels.Index
+GET     /hotels/:id              Hotels.Show
+GET     /hotels/:id/booking      Hotels.Book
+OPTIONS /hotels/:id/booking      Hotels.Book
+`})
+	defer os.RemoveAll(dir)
+	router := NewRouter(filepath.Join(dir, "routes"))
+	if err := router.Refresh(); err != nil {
+		t.Fatalf("Failed to load routes: %s", err)
+	}
+	defer func(r *Router) { MainRouter = r }(MainRouter)
+	MainRouter = router
+
+	options := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		handle(resp, httptest.NewRequest("OPTIONS", path, nil))
+		return resp
+	}
+
+	eq(t, "disabled", options("/hotels").Code, http.StatusNotFound)
+
+	Config.SetOption("router.autooptions", "true")
+	resp := options("/hotels")
+	eq(t, "status", resp.Code, http.StatusNoContent)
+	eq(t, "allow", resp.Header().Get("Allow"), "GET, HEAD, POST, OPTIONS")
+	eq(t, "allow with a param", options("/hotels/3").Header().Get("Allow"), "GET, HEAD, OPTIONS")
+	eq(t, "unrouted", options("/nowhere").Code, http.StatusNotFound)
+
+	// The explicit OPTIONS route takes precedence
+	resp = options("/hotels/3/booking")
+	eq(t, "explicit status", resp.Code, http.StatusOK)
+	eq(t, "explicit allow", resp.Header().Get("Allow"), "")
+}
+
 // Helpers
 
 func eq(t *testing.T, name string, a, b interface{}) bool {
@@ -5,13 +5,19 @@
 package revel
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/websocket"
@@ -23,38 +29,260 @@ var (
 	MainTemplateLoader *TemplateLoader
 	MainWatcher        *Watcher
 	Server             *http.Server
+
+	// The listeners of the running server, closed once it has been stopped
+	serverListeners []net.Listener
+	serverStopped   chan struct{}
+	serverLock      sync.Mutex
+
+	// The port plain HTTP requests are redirected to, see server.httpsredirect
+	httpsRedirectPort string
+
+	// ListenerFactory creates the listeners of the server when set, instead of
+	// net.Listen, e.g. for in-memory listeners in tests or other transports.
+	ListenerFactory func(network, address string) (net.Listener, error)
+
+	// Handlers served ahead of the filter chain, see AddHTTPMux
+	httpMux     = map[string]http.Handler{}
+	httpMuxLock sync.RWMutex
 )
 
+var (
+	// responseHeaders are added to every response, see loadResponseHeaders
+	responseHeaders     map[string]string
+	responseHeadersLock sync.RWMutex
+
+	// Only requests taking longer are logged, see loadSlowRequestThreshold
+	slowRequestThreshold     time.Duration
+	slowRequestThresholdLock sync.RWMutex
+)
+
+func init() {
+	OnAppStart(loadResponseHeaders)
+	OnConfigReload(loadResponseHeaders)
+	OnAppStart(loadSlowRequestThreshold)
+	OnConfigReload(loadSlowRequestThreshold)
+}
+
+// loadSlowRequestThreshold reads log.slow.threshold, a duration like 500ms.
+// When it is set, the request log only has the requests taking longer.
+func loadSlowRequestThreshold() {
+	var duration time.Duration
+	if threshold := Config.StringDefault("log.slow.threshold", ""); threshold != "" {
+		var err error
+		if duration, err = time.ParseDuration(threshold); err != nil {
+			WARN.Printf("Config: ignoring log.slow.threshold, %q is not a duration", threshold)
+			duration = 0
+		}
+	}
+
+	slowRequestThresholdLock.Lock()
+	slowRequestThreshold = duration
+	slowRequestThresholdLock.Unlock()
+}
+
+// loadResponseHeaders reads the headers added to every response from the
+// response.headers.* options, e.g. response.headers.X-App-Version = 1.2
+func loadResponseHeaders() {
+	const prefix = "response.headers."
+	headers := map[string]string{}
+	for _, key := range Config.Options(prefix) {
+		headers[http.CanonicalHeaderKey(key[len(prefix):])] = Config.StringDefault(key, "")
+	}
+
+	responseHeadersLock.Lock()
+	responseHeaders = headers
+	responseHeadersLock.Unlock()
+}
+
+// addResponseHeaders adds the configured headers which the action has not
+// set itself.
+func addResponseHeaders(header http.Header) {
+	responseHeadersLock.RLock()
+	headers := responseHeaders
+	responseHeadersLock.RUnlock()
+	for name, value := range headers {
+		if _, found := header[name]; !found {
+			header.Set(name, value)
+		}
+	}
+}
+
+// AddHTTPMux registers a handler for the given path, which is served ahead
+// of the filter chain (no routing, session, etc.). A path ending in a slash
+// matches every path below it. Registering a path again replaces the
+// previous handler.
+func AddHTTPMux(path string, handler http.Handler) {
+	httpMuxLock.Lock()
+	defer httpMuxLock.Unlock()
+	httpMux[path] = handler
+}
+
+// findHTTPMux returns the handler registered for the path, preferring an
+// exact match over the longest matching prefix. Returns nil if none is found.
+func findHTTPMux(path string) http.Handler {
+	httpMuxLock.RLock()
+	defer httpMuxLock.RUnlock()
+	if len(httpMux) == 0 {
+		return nil
+	}
+	if handler, found := httpMux[path]; found {
+		return handler
+	}
+
+	var (
+		handler http.Handler
+		longest int
+	)
+	for muxPath, muxHandler := range httpMux {
+		if strings.HasSuffix(muxPath, "/") && strings.HasPrefix(path, muxPath) && len(muxPath) > longest {
+			handler, longest = muxHandler, len(muxPath)
+		}
+	}
+	return handler
+}
+
 // This method handles all requests.  It dispatches to handleInternal after
 // handling / adapting websocket connections.
 func Handle(w http.ResponseWriter, r *http.Request) {
 	handle(w, r)
 }
 func handle(w http.ResponseWriter, r *http.Request) {
+	if IsDraining() {
+		w.Header().Set("Connection", "close")
+	}
+
+	if url, ok := httpsRedirectURL(r); ok {
+		http.Redirect(w, r, url, http.StatusMovedPermanently)
+		return
+	}
+
+	if handler := findHTTPMux(r.URL.Path); handler != nil {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
 	if maxRequestSize := int64(Config.IntDefault("http.maxrequestsize", 0)); maxRequestSize > 0 {
 		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
 	}
 
 	upgrade := r.Header.Get("Upgrade")
 	if upgrade == "websocket" || upgrade == "Websocket" {
-		websocket.Handler(func(ws *websocket.Conn) {
-			//Override default Read/Write timeout with sane value for a web socket request
-			if err := ws.SetDeadline(time.Now().Add(time.Hour * 24)); err != nil {
-				ERROR.Println("SetDeadLine failed:", err)
+		if !Config.BoolDefault("websocket.enabled", true) {
+			http.Error(w, "Websockets are not enabled", http.StatusBadRequest)
+			return
+		}
+		// Close websockets without any traffic, so they don't block a shutdown
+		if timeout := time.Duration(Config.IntDefault("websocket.idle.timeout", 0)) * time.Second; timeout > 0 {
+			w = &idleTimeoutResponseWriter{w, timeout}
+		}
+		// The websocket counts against server.maxconnperip until it is closed
+		defer keepConnCounted(r)()
+		// Counted while the server still tracks the connection, so a shutdown
+		// waiting for the websockets can't miss it
+		websocketConns.Add(1)
+		defer websocketConns.Done()
+		// Compressing the messages when websocket.compress is on and the
+		// client offers it
+		handshake := checkWebsocketOrigin
+		if Config.BoolDefault("websocket.compress", false) && websocketDeflateOffered(r.Header) {
+			w = &deflateResponseWriter{w}
+			handshake = func(config *websocket.Config, req *http.Request) error {
+				if err := checkWebsocketOrigin(config, req); err != nil {
+					return err
+				}
+				config.Header = http.Header{"Sec-Websocket-Extensions": {websocketDeflateExtension}}
+				return nil
 			}
-			r.Method = "WS"
-			handleInternal(w, r, ws)
-		}).ServeHTTP(w, r)
+		}
+		websocket.Server{
+			Handshake: handshake,
+			Handler: func(ws *websocket.Conn) {
+				defer trackWebsocket(ws)()
+
+				//Override default Read/Write timeout with sane value for a web socket request
+				if err := ws.SetDeadline(time.Now().Add(time.Hour * 24)); err != nil {
+					ERROR.Println("SetDeadLine failed:", err)
+				}
+				r.Method = "WS"
+				handleInternal(w, r, ws)
+			},
+		}.ServeHTTP(w, r)
 	} else {
 		handleInternal(w, r, nil)
 	}
 }
 
+// httpsRedirectURL returns the https URL for a plain HTTP GET or HEAD request
+// when server.httpsredirect is on and the server listens with both TLS and
+// plain HTTP. Other methods are served as they are, since a redirect would
+// lose their body.
+func httpsRedirectURL(r *http.Request) (string, bool) {
+	if r.TLS != nil || (r.Method != "GET" && r.Method != "HEAD") {
+		return "", false
+	}
+	serverLock.Lock()
+	port := httpsRedirectPort
+	serverLock.Unlock()
+	if port == "" {
+		return "", false
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	return "https://" + host + r.URL.RequestURI(), true
+}
+
 func handleInternal(w http.ResponseWriter, r *http.Request, ws *websocket.Conn) {
 	// TODO For now this okay to put logger here for all the requests
 	// However, it's best to have logging handler at server entry level
 	start := time.Now()
 	clientIP := ClientIP(r)
+	atomic.AddInt64(&inFlightRequests, 1)
+	defer atomic.AddInt64(&inFlightRequests, -1)
+
+	// Keep browsers from sniffing a content type other than the one sent
+	if Config.BoolDefault("response.nosniff", false) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+
+	// The server's writer, for the informational responses
+	informational := w
+
+	if InMaintenance() && !maintenanceAllowed(clientIP) {
+		writeMaintenance(w)
+		return
+	}
+
+	// Cap the size of the response body if configured, aborting the response
+	// once it is exceeded
+	if maxSize := int64(Config.IntDefault("response.maxsize", 0)); maxSize > 0 {
+		limited := &maxSizeResponseWriter{ResponseWriter: w, remaining: maxSize}
+		w = limited
+		defer func() {
+			if limited.exceeded {
+				ERROR.Printf("Response to %s %s exceeded response.maxsize of %d bytes, aborting", r.Method, r.URL.Path, maxSize)
+				panic(http.ErrAbortHandler)
+			}
+		}()
+	}
+
+	// Respond to HEAD requests like to GET requests, without the body
+	if r.Method == "HEAD" {
+		w = &headResponseWriter{ResponseWriter: w}
+	}
+
+	// Buffer the body for the filters to inspect if configured to do so
+	var capture *captureResponseWriter
+	if ws == nil && Config.BoolDefault("response.capture", false) {
+		capture = newCaptureResponseWriter(w)
+		w = capture
+	}
 
 	var (
 		req  = NewRequest(r)
@@ -63,11 +291,29 @@ func handleInternal(w http.ResponseWriter, r *http.Request, ws *websocket.Conn)
 	)
 	req.Websocket = ws
 	c.ClientIP = clientIP
+	resp.capture = capture
+	resp.informational = informational
+
+	// The finish funcs run once the response is done, even when it panics
+	for _, hook := range requestHooks {
+		if finish := hook(c); finish != nil {
+			defer finish()
+		}
+	}
 
 	Filters[0](c, Filters[1:])
+	addResponseHeaders(resp.Out.Header())
 	if c.Result != nil {
+		// A result set after the action's has been captured replaces it
+		if capture != nil {
+			capture.reset()
+		}
 		c.Result.Apply(req, resp)
-	} else if c.Response.Status != 0 {
+	}
+	if capture != nil {
+		capture.flush(resp.Out)
+	}
+	if c.Result == nil && c.Response.Status != 0 && (capture == nil || capture.status == 0) {
 		c.Response.Out.WriteHeader(c.Response.Status)
 	}
 	// Close the Writer if we can
@@ -75,17 +321,44 @@ func handleInternal(w http.ResponseWriter, r *http.Request, ws *websocket.Conn)
 		_ = w.Close()
 	}
 
+	duration := time.Since(start)
+	if metricsEnabled {
+		metrics.observe(c.Response.Status, duration)
+	}
+
+	// With a slow request threshold only the slower requests are logged,
+	// tagged as such
+	tag := ""
+	slowRequestThresholdLock.RLock()
+	threshold := slowRequestThreshold
+	slowRequestThresholdLock.RUnlock()
+	if threshold > 0 {
+		if duration < threshold {
+			return
+		}
+		tag = " SLOW"
+	}
+
+	// Log the pattern of the matched route rather than the concrete path, so
+	// the requests to a route can be grouped
+	path := r.URL.Path
+	if req.RoutePath != "" {
+		path = req.RoutePath
+	}
+
 	// Revel request access log format
 	// RequestStartTime ClientIP ResponseStatus RequestLatency HTTPMethod URLPath
 	// Sample format:
 	// 2016/05/25 17:46:37.112 127.0.0.1 200  270.157µs GET /
-	requestLog.Printf("%v %v %v %10v %v %v",
+	requestLog.Printf("%v %v %v %10v %v %v%v%v",
 		start.Format(requestLogTimeFormat),
 		clientIP,
 		c.Response.Status,
-		time.Since(start),
+		duration,
 		r.Method,
-		r.URL.Path,
+		path,
+		requestLogDetails(r),
+		tag,
 	)
 }
 
@@ -109,19 +382,54 @@ func InitServer() http.HandlerFunc {
 		Filters = append([]Filter{WatchFilter}, Filters...)
 	}
 
-	// If desired (or by default), create a watcher for templates and routes.
 	// The watcher calls Refresh() on things on the first request.
-	if MainWatcher != nil && Config.BoolDefault("watch.templates", true) {
+	if MainWatcher != nil && watchTemplates() {
 		MainWatcher.Listen(MainTemplateLoader, MainTemplateLoader.paths...)
 	}
 
 	return http.HandlerFunc(handle)
 }
 
+// watchTemplates reports whether the templates are watched, if desired or
+// by default in dev mode.
+func watchTemplates() bool {
+	return Config.BoolDefault("watch", true) && Config.BoolDefault("watch.templates", DevMode)
+}
+
+// newServer returns the http.Server serving Revel on the given address,
+// configured from app.conf.
+func newServer(localAddress string) *http.Server {
+	return &http.Server{
+		Addr:         localAddress,
+		Handler:      http.HandlerFunc(handle),
+		ReadTimeout:  time.Duration(Config.IntDefault("http.timeout.read", 0)) * time.Second,
+		WriteTimeout: time.Duration(Config.IntDefault("http.timeout.write", 0)) * time.Second,
+		// Zero keeps the idle keep-alive connections for the read timeout
+		IdleTimeout: time.Duration(Config.IntDefault("http.timeout.idle", 0)) * time.Second,
+		// Zero uses http.DefaultMaxHeaderBytes
+		MaxHeaderBytes: Config.IntDefault("server.maxheaderbytes", 0),
+		ConnState:      trackConn,
+	}
+}
+
 // Run the server.
 // This is called from the generated main file.
 // If port is non-zero, use that.  Else, read the port from app.conf.
 func Run(port int) {
+	if err := RunWithError(port); err != nil {
+		ERROR.Println(err)
+		FlushLogs()
+		os.Exit(1)
+	}
+}
+
+// RunWithError runs the server like Run, but returns the error instead of
+// exiting when the server fails to start or serve. It returns nil once the
+// server has been stopped by Stop.
+//
+// The server listens on http.addresses when it is set, instead of http.addr
+// and http.port, see parseListenAddresses.
+func RunWithError(port int) error {
 	address := HTTPAddr
 	if port == 0 {
 		port = HTTPPort
@@ -141,34 +449,270 @@ func Run(port int) {
 		localAddress = address + ":" + strconv.Itoa(port)
 	}
 
-	Server = &http.Server{
-		Addr:         localAddress,
-		Handler:      http.HandlerFunc(handle),
-		ReadTimeout:  time.Duration(Config.IntDefault("http.timeout.read", 0)) * time.Second,
-		WriteTimeout: time.Duration(Config.IntDefault("http.timeout.write", 0)) * time.Second,
+	addresses := []listenAddress{{network: network, address: localAddress, tls: HTTPSsl}}
+	if value := Config.StringDefault("http.addresses", ""); value != "" {
+		var err error
+		if addresses, err = parseListenAddresses(value); err != nil {
+			return err
+		}
 	}
 
 	InitServer()
+	defer handleSignals()()
+
+	if err := listen(addresses...); err != nil {
+		return err
+	}
 
 	go func() {
 		time.Sleep(100 * time.Millisecond)
-		fmt.Printf("Listening on %s...\n", Server.Addr)
+		fmt.Printf("Listening on %s...\n", strings.Join(ListenAddrs(), ", "))
+	}()
+
+	return serve()
+}
+
+// RunTest starts the server on a free port of 127.0.0.1 without blocking,
+// for integration tests which need a real server. It returns the address
+// listened on and a function stopping the server. Signals are not handled.
+func RunTest() (addr string, stop func(), err error) {
+	InitServer()
+	if err = listen(listenAddress{network: "tcp", address: "127.0.0.1:0"}); err != nil {
+		return "", nil, err
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve()
 	}()
 
-	if HTTPSsl {
-		if network != "tcp" {
-			// This limitation is just to reduce complexity, since it is standard
-			// to terminate SSL upstream when using unix domain sockets.
-			ERROR.Fatalln("SSL is only supported for TCP sockets. Specify a port to listen on.")
+	stop = func() {
+		if err := Stop(); err != nil {
+			ERROR.Println("Failed to stop:", err)
 		}
-		ERROR.Fatalln("Failed to listen:",
-			Server.ListenAndServeTLS(HTTPSslCert, HTTPSslKey))
-	} else {
-		listener, err := net.Listen(network, Server.Addr)
+		if err := <-served; err != nil {
+			ERROR.Println(err)
+		}
+	}
+	return ListenAddr(), stop, nil
+}
+
+// ListenAddr returns the address the server is listening on, which differs
+// from the configured one when listening on port 0. It returns an empty
+// string when the server is not running. With several http.addresses it is
+// the first one, see ListenAddrs.
+func ListenAddr() string {
+	if addrs := ListenAddrs(); len(addrs) > 0 {
+		return addrs[0]
+	}
+	return ""
+}
+
+// ListenAddrs returns the addresses of all the listeners of the server.
+func ListenAddrs() []string {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	addrs := make([]string, 0, len(serverListeners))
+	for _, listener := range serverListeners {
+		addrs = append(addrs, listener.Addr().String())
+	}
+	return addrs
+}
+
+// Stop shuts the running server down gracefully, waiting for the in-flight
+// requests and websockets to complete, which makes Run return. The
+// websockets still open after websocket.shutdown.timeout seconds (5 by
+// default) are closed. IsDraining reports true until the shutdown is
+// complete.
+func Stop() error {
+	serverLock.Lock()
+	listeners, stopped := serverListeners, serverStopped
+	serverListeners, serverStopped = nil, nil
+	httpsRedirectPort = ""
+	serverLock.Unlock()
+	if stopped == nil {
+		return errors.New("Server is not running")
+	}
+	// Left set once stopped, the next server started resets it
+	atomic.StoreInt32(&draining, 1)
+
+	defer close(stopped)
+	err := Server.Shutdown(context.Background())
+	// Listeners which were not served yet are not closed by the Server
+	for _, listener := range listeners {
+		_ = listener.Close()
+	}
+	waitWebsockets(time.Duration(Config.IntDefault("websocket.shutdown.timeout", 5)) * time.Second)
+	runShutdownHooks()
+	FlushLogs()
+	return err
+}
+
+// listenAddress is an address for the server to listen on.
+type listenAddress struct {
+	network string // e.g. tcp or unix
+	address string // e.g. 0.0.0.0:443 or /tmp/app.socket
+	tls     bool   // Whether to serve TLS using http.sslcert and http.sslkey
+}
+
+// parseListenAddresses parses the comma separated addresses of
+// http.addresses. An address may start with its network like http.addr,
+// otherwise it is TCP, and with "tls:" to serve TLS on it, e.g.
+//
+//	http.addresses = tls:0.0.0.0:443, 127.0.0.1:9001, unix:/tmp/admin.socket
+func parseListenAddresses(value string) ([]listenAddress, error) {
+	var addresses []listenAddress
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		addr := listenAddress{network: "tcp"}
+		if strings.HasPrefix(entry, "tls:") {
+			addr.tls, entry = true, entry[len("tls:"):]
+		}
+		if parts := strings.SplitN(entry, ":", 2); len(parts) == 2 {
+			switch parts[0] {
+			case "tcp", "tcp4", "tcp6", "unix":
+				addr.network, entry = parts[0], parts[1]
+			}
+		}
+		if entry == "" {
+			return nil, fmt.Errorf("Invalid http.addresses entry: no address in %q", value)
+		}
+		addr.address = entry
+		addresses = append(addresses, addr)
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("Invalid http.addresses: no addresses")
+	}
+	return addresses, nil
+}
+
+// listen creates the Server and its listeners on the addresses, which all
+// serve the same handler.
+func listen(addresses ...listenAddress) error {
+	Server = newServer(addresses[0].address)
+	listeners := make([]net.Listener, 0, len(addresses))
+	closeListeners := func() {
+		for _, listener := range listeners {
+			_ = listener.Close()
+		}
+	}
+
+	newListener := net.Listen
+	if ListenerFactory != nil {
+		newListener = ListenerFactory
+	}
+	for _, addr := range addresses {
+		listener, err := newListener(addr.network, addr.address)
 		if err != nil {
-			ERROR.Fatalln("Failed to listen:", err)
+			closeListeners()
+			return fmt.Errorf("Failed to listen: %s", err)
+		}
+		listeners = append(listeners, listener)
+
+		if addr.tls {
+			if !strings.HasPrefix(addr.network, "tcp") {
+				// This limitation is just to reduce complexity, since it is standard
+				// to terminate SSL upstream when using unix domain sockets.
+				closeListeners()
+				return errors.New("SSL is only supported for TCP sockets. Specify a port to listen on.")
+			}
+			cert, err := tls.LoadX509KeyPair(HTTPSslCert, HTTPSslKey)
+			if err != nil {
+				closeListeners()
+				return fmt.Errorf("Failed to load certificate: %s", err)
+			}
+			// Every TLS listener gets a config of its own
+			config := &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				NextProtos:   []string{"h2", "http/1.1"},
+			}
+			if Server.TLSConfig == nil {
+				Server.TLSConfig = config
+			}
+			listeners[len(listeners)-1] = tls.NewListener(listener, config)
+		}
+	}
+
+	// Redirect the plain listeners to the first TLS one
+	var redirectPort string
+	if Config.BoolDefault("server.httpsredirect", false) {
+		var plain bool
+		for i, addr := range addresses {
+			if !addr.tls {
+				plain = true
+			} else if redirectPort == "" {
+				_, redirectPort, _ = net.SplitHostPort(listeners[i].Addr().String())
+			}
+		}
+		if !plain {
+			redirectPort = ""
 		}
-		ERROR.Fatalln("Failed to serve:", Server.Serve(listener))
+	}
+
+	serverLock.Lock()
+	serverListeners, serverStopped = listeners, make(chan struct{})
+	httpsRedirectPort = redirectPort
+	serverLock.Unlock()
+	atomic.StoreInt32(&draining, 0)
+	return nil
+}
+
+// serve serves the listeners until the server fails or is stopped. When one
+// of them fails the server is closed.
+func serve() error {
+	serverLock.Lock()
+	listeners, stopped := serverListeners, serverStopped
+	serverLock.Unlock()
+	if stopped == nil {
+		// Stopped before serving
+		return nil
+	}
+
+	served := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			served <- Server.Serve(listener)
+		}(listener)
+	}
+	var failed error
+	for range listeners {
+		if err := <-served; err != http.ErrServerClosed && failed == nil {
+			failed = fmt.Errorf("Failed to serve: %s", err)
+			_ = Server.Close()
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+
+	// Wait for the in-flight requests to complete
+	<-stopped
+	INFO.Println("Server stopped")
+	return nil
+}
+
+// OnAppStop registers a function to be run once the server has been stopped,
+// after the in-flight requests have completed and before Run returns. The
+// functions are run in the order they were registered.
+func OnAppStop(f func()) {
+	shutdownHooks = append(shutdownHooks, f)
+}
+
+// OnRequest registers a function run for every request dispatched to the
+// filters, e.g. to start a tracing span. The function it returns, if not
+// nil, is run once the response has been written or the request panicked,
+// to finish the span. The finish functions run in the reverse order.
+func OnRequest(f func(c *Controller) (finish func())) {
+	requestHooks = append(requestHooks, f)
+}
+
+func runShutdownHooks() {
+	for _, hook := range shutdownHooks {
+		hook()
 	}
 }
 
@@ -188,6 +732,11 @@ type StartupHooks []StartupHook
 
 var startupHooks StartupHooks
 
+// The functions run when the server stops, see OnAppStop
+var shutdownHooks []func()
+
+var requestHooks []func(*Controller) func()
+
 func (slice StartupHooks) Len() int {
 	return len(slice)
 }
@@ -5,12 +5,27 @@
 package revel
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // This tries to benchmark the usual request-serving pipeline to get an overall
@@ -116,9 +131,584 @@ func TestOnAppStart(t *testing.T) {
 	}
 }
 
+func TestTemplateWatcher(t *testing.T) {
+	defer func() { DevMode = false }()
+
+	startFakeBookingApp()
+	Config.SetOption("watch", "true")
+	eq(t, "prod mode", watchTemplates(), false)
+
+	Config.SetOption("watch.templates", "true")
+	eq(t, "prod mode with watch.templates", watchTemplates(), true)
+
+	Config.SetOption("watch", "false")
+	eq(t, "watch disabled", watchTemplates(), false)
+
+	startFakeBookingApp()
+	Config.SetOption("watch", "true")
+	DevMode = true
+	eq(t, "dev mode", watchTemplates(), true)
+
+	Config.SetOption("watch.templates", "false")
+	eq(t, "dev mode without watch.templates", watchTemplates(), false)
+}
+
+func TestNoContent(t *testing.T) {
+	startFakeBookingApp()
+	filters := Filters
+	defer func() { Filters = filters }()
+	Filters = []Filter{func(c *Controller, fc []Filter) {
+		c.Result = c.NoContent()
+	}}
+
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/hotels/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	eq(t, "status", resp.StatusCode, http.StatusNoContent)
+	eq(t, "content type", resp.Header.Get("Content-Type"), "")
+	eq(t, "body", string(body), "")
+}
+
+// Test that the finish func of OnRequest runs once per request, also when
+// the request panics.
+func TestOnRequest(t *testing.T) {
+	startFakeBookingApp()
+	filters := Filters
+	defer func() { Filters = filters }()
+	defer func(hooks []func(*Controller) func()) { requestHooks = hooks }(requestHooks)
+
+	var started, finished []string
+	OnRequest(func(c *Controller) func() {
+		started = append(started, c.Request.URL.Path)
+		return func() { finished = append(finished, c.Request.URL.Path) }
+	})
+	Filters = []Filter{func(c *Controller, fc []Filter) {
+		if c.Request.URL.Path == "/aborted" {
+			panic(http.ErrAbortHandler)
+		}
+		fc[0](c, fc[1:])
+	}, PanicFilter, func(c *Controller, fc []Filter) {
+		if c.Request.URL.Path == "/recovered" {
+			panic("recovered by the PanicFilter")
+		}
+		c.Result = c.RenderText("ok")
+	}}
+
+	serve := func(path string) {
+		defer func() {
+			if err := recover(); err != nil && err != http.ErrAbortHandler {
+				t.Errorf("Unexpected panic: %v", err)
+			}
+		}()
+		handle(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	serve("/hotels")
+	serve("/recovered")
+	serve("/aborted")
+	eq(t, "started", strings.Join(started, " "), "/hotels /recovered /aborted")
+	eq(t, "finished", strings.Join(finished, " "), "/hotels /recovered /aborted")
+}
+
+func TestSlowRequestLog(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("log.slow.threshold", "50ms")
+	loadSlowRequestThreshold()
+	defer func() { slowRequestThreshold = 0 }()
+
+	var logged bytes.Buffer
+	defer func(l *log.Logger) { requestLog = l }(requestLog)
+	requestLog = log.New(&logged, "", 0)
+	filters := Filters
+	defer func() { Filters = filters }()
+	delay := time.Duration(0)
+	Filters = []Filter{func(c *Controller, fc []Filter) {
+		time.Sleep(delay)
+		c.Result = c.NoContent()
+	}}
+
+	handle(httptest.NewRecorder(), showRequest)
+	eq(t, "fast request log", logged.String(), "")
+
+	delay = 100 * time.Millisecond
+	handle(httptest.NewRecorder(), showRequest)
+	if !strings.HasSuffix(logged.String(), "GET /hotels/3 SLOW\n") {
+		t.Errorf("Expected the slow request to be logged, got %q", logged.String())
+	}
+}
+
+func TestRequestLogRoutePath(t *testing.T) {
+	startFakeBookingApp()
+	var logged bytes.Buffer
+	defer func(l *log.Logger) { requestLog = l }(requestLog)
+	requestLog = log.New(&logged, "", 0)
+
+	handle(httptest.NewRecorder(), showRequest)
+	if !strings.HasSuffix(logged.String(), "GET /hotels/:id\n") {
+		t.Errorf("Expected the route pattern to be logged, got %q", logged.String())
+	}
+
+	// Paths without a route are logged as they are
+	logged.Reset()
+	handle(httptest.NewRecorder(), httptest.NewRequest("GET", "/nowhere/42", nil))
+	if !strings.HasSuffix(logged.String(), "GET /nowhere/42\n") {
+		t.Errorf("Expected the unrouted path to be logged, got %q", logged.String())
+	}
+}
+
+func TestResponseMaxSize(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("response.maxsize", "1024")
+	filters := Filters
+	defer func() { Filters = filters }()
+	Filters = []Filter{func(c *Controller, fc []Filter) {
+		c.Result = c.RenderText(strings.Repeat("x", 1<<20))
+	}}
+
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/hotels")
+	var body []byte
+	if err == nil {
+		body, err = ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("Expected the oversized response to be aborted")
+	}
+	if len(body) > 1024 {
+		t.Errorf("Expected at most 1024 bytes, got %d", len(body))
+	}
+
+	// Responses within the limit are unaffected
+	Filters = []Filter{func(c *Controller, fc []Filter) {
+		c.Result = c.RenderText(strings.Repeat("x", 1024))
+	}}
+	resp, err = http.Get(server.URL + "/hotels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	eq(t, "error within limit", err, nil)
+	eq(t, "body within limit", len(body), 1024)
+}
+
+func TestServerMaxHeaderBytes(t *testing.T) {
+	startFakeBookingApp()
+	eq(t, "default max header bytes", 0, newServer(":9000").MaxHeaderBytes)
+
+	Config.SetOption("server.maxheaderbytes", "4096")
+	eq(t, "max header bytes", 4096, newServer(":9000").MaxHeaderBytes)
+}
+
+func TestServerIdleTimeout(t *testing.T) {
+	startFakeBookingApp()
+	eq(t, "default idle timeout", newServer(":9000").IdleTimeout, time.Duration(0))
+
+	Config.SetOption("http.timeout.idle", "90")
+	defer Config.SetOption("http.timeout.idle", "0")
+	eq(t, "idle timeout", newServer(":9000").IdleTimeout, 90*time.Second)
+}
+
 var (
 	showRequest, _      = http.NewRequest("GET", "/hotels/3", nil)
 	staticRequest, _    = http.NewRequest("GET", "/public/js/sessvars.js", nil)
 	jsonRequest, _      = http.NewRequest("GET", "/hotels/3/booking", nil)
 	plaintextRequest, _ = http.NewRequest("GET", "/hotels", nil)
 )
+
+func TestRunTest(t *testing.T) {
+	startFakeBookingApp()
+	addr, stop, err := RunTest()
+	if err != nil {
+		t.Fatalf("Failed to run: %s", err)
+	}
+	eq(t, "listen address", addr, ListenAddr())
+
+	resp, err := http.Get("http://" + addr + "/hotels")
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	_ = resp.Body.Close()
+	eq(t, "status", http.StatusOK, resp.StatusCode)
+
+	stop()
+	eq(t, "stopped listen address", "", ListenAddr())
+	if _, err = http.Get("http://" + addr + "/hotels"); err == nil {
+		t.Error("Expected the request to a stopped server to fail")
+	}
+}
+
+// pipeListener is an in-memory listener, accepting the connections dialed.
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+func (l *pipeListener) Dial(network, address string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+func TestListenerFactory(t *testing.T) {
+	startFakeBookingApp()
+	listener := newPipeListener()
+	var requested string
+	defer func() { ListenerFactory = nil }()
+	ListenerFactory = func(network, address string) (net.Listener, error) {
+		requested = network + " " + address
+		return listener, nil
+	}
+
+	addr, stop, err := RunTest()
+	if err != nil {
+		t.Fatalf("Failed to run: %s", err)
+	}
+	defer stop()
+	eq(t, "requested address", requested, "tcp 127.0.0.1:0")
+	eq(t, "listen address", addr, "pipe")
+
+	client := &http.Client{Transport: &http.Transport{Dial: listener.Dial}}
+	resp, err := client.Get("http://pipe/hotels")
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	eq(t, "status", resp.StatusCode, http.StatusOK)
+	eq(t, "body", string(body), "Hello, World!")
+}
+
+func TestParseListenAddresses(t *testing.T) {
+	addresses, err := parseListenAddresses("tls:0.0.0.0:443, 127.0.0.1:9001,unix:/tmp/admin.socket, tls:tcp6:[::1]:8443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "addresses", fmt.Sprint(addresses),
+		"[{tcp 0.0.0.0:443 true} {tcp 127.0.0.1:9001 false} {unix /tmp/admin.socket false} {tcp6 [::1]:8443 true}]")
+
+	if _, err = parseListenAddresses(" , "); err == nil {
+		t.Error("Expected an error without addresses")
+	}
+	if _, err = parseListenAddresses("tls:"); err == nil {
+		t.Error("Expected an error for an entry without an address")
+	}
+}
+
+func TestListenTLSAndPlain(t *testing.T) {
+	startFakeBookingApp()
+	defer func(cert, key string) { HTTPSslCert, HTTPSslKey = cert, key }(HTTPSslCert, HTTPSslKey)
+	HTTPSslCert, HTTPSslKey = writeTestCertificate(t)
+	defer os.RemoveAll(filepath.Dir(HTTPSslCert))
+
+	InitServer()
+	addresses, _ := parseListenAddresses("tls:127.0.0.1:0, 127.0.0.1:0")
+	if err := listen(addresses...); err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- serve()
+	}()
+	defer func() {
+		if err := Stop(); err != nil {
+			t.Error(err)
+		}
+		if err := <-served; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	addrs := ListenAddrs()
+	eq(t, "listeners", len(addrs), 2)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get("https://" + addrs[0] + "/hotels")
+	if err != nil {
+		t.Fatalf("TLS request failed: %s", err)
+	}
+	_ = resp.Body.Close()
+	eq(t, "TLS status", resp.StatusCode, http.StatusOK)
+	if resp.TLS == nil {
+		t.Error("Expected a TLS connection")
+	}
+
+	resp, err = http.Get("http://" + addrs[1] + "/hotels")
+	if err != nil {
+		t.Fatalf("Plain request failed: %s", err)
+	}
+	_ = resp.Body.Close()
+	eq(t, "plain status", resp.StatusCode, http.StatusOK)
+
+	if resp, err = http.Get("http://" + addrs[0] + "/hotels"); err == nil {
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected plain HTTP to the TLS listener to fail, got %d", resp.StatusCode)
+		}
+	}
+}
+
+// Test that server.httpsredirect redirects plain GET requests to the TLS
+// listener, and serves other methods as they are.
+func TestHTTPSRedirect(t *testing.T) {
+	startFakeBookingApp()
+	defer func(cert, key string) { HTTPSslCert, HTTPSslKey = cert, key }(HTTPSslCert, HTTPSslKey)
+	HTTPSslCert, HTTPSslKey = writeTestCertificate(t)
+	defer os.RemoveAll(filepath.Dir(HTTPSslCert))
+	Config.SetOption("server.httpsredirect", "true")
+	defer Config.SetOption("server.httpsredirect", "false")
+
+	InitServer()
+	addresses, _ := parseListenAddresses("tls:127.0.0.1:0, 127.0.0.1:0")
+	if err := listen(addresses...); err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- serve()
+	}()
+	defer func() {
+		if err := Stop(); err != nil {
+			t.Error(err)
+		}
+		if err := <-served; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	addrs := ListenAddrs()
+	_, port, _ := net.SplitHostPort(addrs[0])
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get("http://" + addrs[1] + "/hotels?page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	eq(t, "redirect status", resp.StatusCode, http.StatusMovedPermanently)
+	eq(t, "redirect location", resp.Header.Get("Location"), "https://127.0.0.1:"+port+"/hotels?page=2")
+
+	resp, err = client.Post("http://"+addrs[1]+"/hotels/3/book", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusMovedPermanently {
+		t.Error("Expected a POST not to be redirected")
+	}
+
+	tlsClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	if resp, err = tlsClient.Get("https://" + addrs[0] + "/hotels"); err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	eq(t, "TLS status", resp.StatusCode, http.StatusOK)
+}
+
+// writeTestCertificate writes a self signed certificate for 127.0.0.1 with
+// its key to a temporary directory, which the caller removes.
+func writeTestCertificate(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "revel-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestHeadRequest(t *testing.T) {
+	startFakeBookingApp()
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+
+	for _, path := range []string{"/hotels/3", "/hotels/3/booking", "/hotels"} {
+		get, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %s", path, err)
+		}
+		_ = get.Body.Close()
+		head, err := http.Head(server.URL + path)
+		if err != nil {
+			t.Fatalf("HEAD %s failed: %s", path, err)
+		}
+		body, _ := ioutil.ReadAll(head.Body)
+		_ = head.Body.Close()
+
+		eq(t, "HEAD "+path+" status", get.StatusCode, head.StatusCode)
+		eq(t, "HEAD "+path+" content type", get.Header.Get("Content-Type"), head.Header.Get("Content-Type"))
+		eq(t, "HEAD "+path+" content length", get.ContentLength, head.ContentLength)
+		eq(t, "HEAD "+path+" body", "", string(body))
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("response.headers.X-App-Version", "1.2")
+	Config.SetOption("response.headers.server", "Revel")
+	loadResponseHeaders()
+	defer func() { responseHeaders = nil }()
+
+	resp := httptest.NewRecorder()
+	handle(resp, showRequest)
+	eq(t, "version header", "1.2", resp.Header().Get("X-App-Version"))
+	eq(t, "server header", "Revel", resp.Header().Get("Server"))
+
+	// An explicitly set header wins
+	filters := Filters
+	defer func() { Filters = filters }()
+	Filters = append([]Filter{func(c *Controller, fc []Filter) {
+		c.Response.Out.Header().Set("Server", "Custom")
+		fc[0](c, fc[1:])
+	}}, Filters...)
+
+	resp = httptest.NewRecorder()
+	handle(resp, showRequest)
+	eq(t, "explicit server header", "Custom", resp.Header().Get("Server"))
+	eq(t, "version header", "1.2", resp.Header().Get("X-App-Version"))
+}
+
+func TestMaxConnPerIP(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("server.maxconnperip", "2")
+	defer Config.SetOption("server.maxconnperip", "0")
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handle))
+	server.Config.ConnState = trackConn
+	server.Start()
+	defer server.Close()
+
+	dial := func(ip string) net.Conn {
+		dialer := net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP(ip)}}
+		conn, err := dialer.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+	// get reports whether the connection answers a request
+	get := func(conn net.Conn) bool {
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := fmt.Fprint(conn, "GET /hotels HTTP/1.1\r\nHost: localhost\r\n\r\n"); err != nil {
+			return false
+		}
+		buf := make([]byte, 12)
+		n, _ := conn.Read(buf)
+		return string(buf[:n]) == "HTTP/1.1 200"
+	}
+
+	first, second, third := dial("127.0.0.1"), dial("127.0.0.1"), dial("127.0.0.1")
+	defer first.Close()
+	defer second.Close()
+	defer third.Close()
+	eq(t, "first connection", get(first), true)
+	eq(t, "second connection", get(second), true)
+	eq(t, "connection over the limit", get(third), false)
+
+	other := dial("127.0.0.2")
+	defer other.Close()
+	eq(t, "connection of another IP", get(other), true)
+
+	// Closing a connection makes room for another
+	_ = first.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn := dial("127.0.0.1")
+		ok := get(conn)
+		_ = conn.Close()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("The closed connection was not released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRequestLogRedact(t *testing.T) {
+	startFakeBookingApp()
+	var logged bytes.Buffer
+	defer func(l *log.Logger) { requestLog = l }(requestLog)
+	requestLog = log.New(&logged, "", 0)
+	Config.SetOption("log.request.query", "true")
+	Config.SetOption("log.request.headers", "User-Agent, Authorization")
+	Config.SetOption("log.redact", "token, Email, authorization")
+	defer func() {
+		Config.SetOption("log.request.query", "false")
+		Config.SetOption("log.request.headers", "")
+		Config.SetOption("log.redact", "")
+	}()
+
+	r := httptest.NewRequest("GET", "/nowhere?page=2&token=s3cr3t&EMAIL=a%40b.c&q=x", nil)
+	r.Header.Set("User-Agent", "tester")
+	r.Header.Set("Authorization", "Bearer s3cr3t")
+	handle(httptest.NewRecorder(), r)
+	if !strings.HasSuffix(logged.String(), "GET /nowhere?page=2&token=***&EMAIL=***&q=x User-Agent=tester Authorization=***\n") {
+		t.Errorf("Expected the secrets to be redacted, got %q", logged.String())
+	}
+	if strings.Contains(logged.String(), "s3cr3t") {
+		t.Errorf("Secret leaked into the access log: %q", logged.String())
+	}
+}
@@ -147,6 +147,11 @@ func GetSessionFromCookie(cookie *http.Cookie) Session {
 // SessionFilter is a Revel Filter that retrieves and sets the session cookie.
 // Within Revel, it is available as a Session attribute on Controller instances.
 // The name of the Session cookie is set as CookiePrefix + "_SESSION".
+//
+// Browsers drop cookies larger than about 4KB, so a warning is logged when the
+// session cookie exceeds session.maxcookiesize (in bytes, 4096 by default).
+// With session.maxcookiesize.reject the cookie is not sent, and the response
+// is replaced by an internal server error instead.
 func SessionFilter(c *Controller, fc []Filter) {
 	c.Session = restoreSession(c.Request.Request)
 	sessionWasEmpty := len(c.Session) == 0
@@ -158,7 +163,17 @@ func SessionFilter(c *Controller, fc []Filter) {
 
 	// Store the signed session if it could have changed.
 	if len(c.Session) > 0 || !sessionWasEmpty {
-		c.SetCookie(c.Session.Cookie())
+		cookie := c.Session.Cookie()
+		if maxSize := Config.IntDefault("session.maxcookiesize", 4096); maxSize > 0 && len(cookie.String()) > maxSize {
+			WARN.Printf("Session cookie of %d bytes exceeds session.maxcookiesize of %d bytes (%s %s)",
+				len(cookie.String()), maxSize, c.Request.Method, c.Request.URL.Path)
+			if Config.BoolDefault("session.maxcookiesize.reject", false) {
+				c.Response.Status = http.StatusInternalServerError
+				c.Result = c.RenderError(fmt.Errorf("Session cookie exceeds %d bytes", maxSize))
+				return
+			}
+		}
+		c.SetCookie(cookie)
 	}
 }
 
@@ -5,7 +5,11 @@
 package revel
 
 import (
+	"bytes"
+	"log"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -66,3 +70,47 @@ func TestSessionExpire(t *testing.T) {
 		t.Error("expect expires", cookie.Expires, "before", expectExpire)
 	}
 }
+
+func TestSessionMaxCookieSize(t *testing.T) {
+	startFakeBookingApp()
+	var warned bytes.Buffer
+	WARN = log.New(&warned, "", 0)
+	Config.SetOption("session.maxcookiesize", "512")
+	defer func() {
+		Config.SetOption("session.maxcookiesize", "4096")
+		Config.SetOption("session.maxcookiesize.reject", "false")
+	}()
+
+	run := func(value string) (*httptest.ResponseRecorder, *Controller) {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		SessionFilter(c, []Filter{func(c *Controller, _ []Filter) {
+			c.Session["data"] = value
+			c.Result = c.RenderText("ok")
+		}})
+		return resp, c
+	}
+
+	resp, _ := run("small")
+	eq(t, "small warning", warned.String(), "")
+	if resp.Header().Get("Set-Cookie") == "" {
+		t.Error("Expected the small session cookie to be set")
+	}
+
+	resp, c := run(strings.Repeat("x", 1024))
+	if !strings.Contains(warned.String(), "exceeds session.maxcookiesize of 512 bytes") {
+		t.Errorf("Expected a warning, got %q", warned.String())
+	}
+	if resp.Header().Get("Set-Cookie") == "" {
+		t.Error("Expected the oversized session cookie to be set without rejection")
+	}
+	eq(t, "result without rejection", c.Response.Status, http.StatusOK)
+
+	Config.SetOption("session.maxcookiesize.reject", "true")
+	resp, c = run(strings.Repeat("x", 1024))
+	eq(t, "rejected cookie", resp.Header().Get("Set-Cookie"), "")
+	eq(t, "rejected status", c.Response.Status, http.StatusInternalServerError)
+	if _, ok := c.Result.(ErrorResult); !ok {
+		t.Errorf("Expected an error result, got %#v", c.Result)
+	}
+}
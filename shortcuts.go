@@ -0,0 +1,80 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Built in handlers for the files every browser and crawler asks for.
+// They are opt-in, a handler is only registered when its key is present in
+// app.conf, e.g.
+//
+//	server.favicon = public/img/favicon.ico
+//	server.robots  = User-agent: *\nDisallow: /admin
+//
+// The favicon is a file path, the robots value may either be a file path or
+// the literal body (with \n as line separator). Relative paths are resolved
+// against the application base path. A present but empty key responds with
+// a 404, which avoids the log spam of a full dispatch.
+var shortcutHandlers = []struct {
+	path, key string
+	handler   http.HandlerFunc
+}{
+	{"/favicon.ico", "server.favicon", faviconHandler},
+	{"/robots.txt", "server.robots", robotsHandler},
+}
+
+func init() {
+	OnAppStart(registerShortcutHandlers)
+}
+
+// registerShortcutHandlers adds the shortcut handlers which are configured.
+func registerShortcutHandlers() {
+	for _, shortcut := range shortcutHandlers {
+		if _, found := Config.String(shortcut.key); found {
+			AddHTTPMux(shortcut.path, shortcut.handler)
+		}
+	}
+}
+
+func faviconHandler(w http.ResponseWriter, r *http.Request) {
+	favicon := Config.StringDefault("server.favicon", "")
+	if favicon == "" {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, shortcutPath(favicon))
+}
+
+func robotsHandler(w http.ResponseWriter, r *http.Request) {
+	robots := Config.StringDefault("server.robots", "")
+	if robots == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if path := shortcutPath(robots); !strings.Contains(robots, "\\n") {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			http.ServeFile(w, r, path)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := w.Write([]byte(strings.Replace(robots, "\\n", "\n", -1))); err != nil {
+		ERROR.Println("Response write failed:", err)
+	}
+}
+
+// shortcutPath resolves a configured file path against the base path.
+func shortcutPath(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(BasePath, path)
+	}
+	return path
+}
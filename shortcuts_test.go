@@ -0,0 +1,67 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestShortcutHandlersConfigured(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("server.favicon", "public/js/sessvars.js")
+	Config.SetOption("server.robots", "User-agent: *\\nDisallow: /admin")
+	registerShortcutHandlers()
+
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/favicon.ico", nil))
+	expected, _ := ioutil.ReadFile(filepath.Join(BasePath, "public/js/sessvars.js"))
+	eq(t, "favicon status", http.StatusOK, resp.Code)
+	eq(t, "favicon body", string(expected), resp.Body.String())
+
+	resp = httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/robots.txt", nil))
+	eq(t, "robots status", http.StatusOK, resp.Code)
+	eq(t, "robots body", "User-agent: *\nDisallow: /admin", resp.Body.String())
+}
+
+func TestShortcutHandlersUnconfigured(t *testing.T) {
+	startFakeBookingApp()
+	if _, found := Config.String("server.favicon"); found {
+		t.Fatal("Expected server.favicon to be unconfigured in the test app")
+	}
+	if _, found := Config.String("server.robots"); found {
+		t.Fatal("Expected server.robots to be unconfigured in the test app")
+	}
+	httpMuxLock.Lock()
+	for _, shortcut := range shortcutHandlers {
+		delete(httpMux, shortcut.path)
+	}
+	httpMuxLock.Unlock()
+	registerShortcutHandlers()
+
+	for _, path := range []string{"/favicon.ico", "/robots.txt"} {
+		if findHTTPMux(path) != nil {
+			t.Errorf("Expected no shortcut handler for %s", path)
+		}
+	}
+}
+
+// Test that a present but empty key responds with a 404.
+func TestShortcutHandlersEmpty(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("server.favicon", "")
+	Config.SetOption("server.robots", "")
+	registerShortcutHandlers()
+
+	for _, path := range []string{"/favicon.ico", "/robots.txt"} {
+		resp := httptest.NewRecorder()
+		handle(resp, httptest.NewRequest("GET", path, nil))
+		eq(t, path+" status", http.StatusNotFound, resp.Code)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// The signals handled by Run are configured in app.conf by a comma separated
+// list of signal names or numbers, so they can be moved out of the way of
+// other tooling sending signals to the process:
+//
+//	signal.reload    Reloads the config, see ReloadConfig. Default: SIGHUP
+//	signal.shutdown  Stops the server gracefully, see Stop. A second signal
+//	                 exits immediately. Default: SIGINT,SIGTERM
+//
+// An empty list leaves the signals to their default behaviour.
+var signalNames = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// configSignals returns the signals listed in the config key.
+func configSignals(key, dfault string) []os.Signal {
+	var signals []os.Signal
+	for _, name := range strings.Split(Config.StringDefault(key, dfault), ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !strings.HasPrefix(name, "SIG") {
+			if n, err := strconv.Atoi(name); err == nil {
+				signals = append(signals, syscall.Signal(n))
+				continue
+			}
+			name = "SIG" + name
+		}
+		if sig, ok := signalNames[name]; ok {
+			signals = append(signals, sig)
+		} else {
+			WARN.Printf("Config: ignoring unknown signal %s in %s", name, key)
+		}
+	}
+	return signals
+}
+
+// handleSignals starts handling the configured signals. It returns a function
+// restoring their default behaviour.
+func handleSignals() (release func()) {
+	reloads := make(chan os.Signal, 1)
+	if signals := configSignals("signal.reload", "SIGHUP"); len(signals) > 0 {
+		signal.Notify(reloads, signals...)
+	}
+	shutdowns := make(chan os.Signal, 1)
+	if signals := configSignals("signal.shutdown", "SIGINT,SIGTERM"); len(signals) > 0 {
+		signal.Notify(shutdowns, signals...)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		stopping := false
+		for {
+			select {
+			case <-reloads:
+				if err := ReloadConfig(); err != nil {
+					ERROR.Println("Failed to reload config:", err)
+				}
+			case sig := <-shutdowns:
+				if stopping {
+					ERROR.Printf("Received %s while stopping, exiting", sig)
+					FlushLogs()
+					os.Exit(1)
+				}
+				stopping = true
+				INFO.Printf("Received %s, stopping", sig)
+				go func() {
+					if err := Stop(); err != nil {
+						ERROR.Println("Failed to stop:", err)
+					}
+				}()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(reloads)
+		signal.Stop(shutdowns)
+		close(done)
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package revel
+
+import "syscall"
+
+func init() {
+	signalNames["SIGUSR1"] = syscall.SIGUSR1
+	signalNames["SIGUSR2"] = syscall.SIGUSR2
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package revel
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConfigSignals(t *testing.T) {
+	startFakeBookingApp()
+	eq(t, "default", fmt.Sprint([]os.Signal{syscall.SIGINT, syscall.SIGTERM}), fmt.Sprint(configSignals("signal.shutdown", "SIGINT,SIGTERM")))
+
+	Config.SetOption("signal.shutdown", "usr2, 15, SIGBOGUS")
+	eq(t, "configured", fmt.Sprint([]os.Signal{syscall.SIGUSR2, syscall.SIGTERM}), fmt.Sprint(configSignals("signal.shutdown", "SIGINT,SIGTERM")))
+
+	Config.SetOption("signal.shutdown", "")
+	eq(t, "disabled", 0, len(configSignals("signal.shutdown", "SIGINT,SIGTERM")))
+}
+
+func TestHandleSignals(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("signal.reload", "SIGUSR1")
+	Config.SetOption("signal.shutdown", "SIGUSR2")
+	hooks := configReloadHooks
+	defer func() { configReloadHooks = hooks }()
+	reloaded := make(chan struct{}, 1)
+	OnConfigReload(func() { reloaded <- struct{}{} })
+
+	InitServer()
+	if err := listen(listenAddress{network: "tcp", address: "127.0.0.1:0"}); err != nil {
+		t.Fatal(err)
+	}
+	release := handleSignals()
+	defer release()
+	served := make(chan error, 1)
+	go func() {
+		served <- serve()
+	}()
+
+	// The reloaded config drops the options set above, keep using them
+	Config.SetOverride("signal.shutdown", "SIGUSR2")
+	defer Config.RemoveOverride("signal.shutdown")
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The reload signal did not reload the config")
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-served:
+		eq(t, "serve error", nil, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("The shutdown signal did not stop the server")
+	}
+}
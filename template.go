@@ -46,6 +46,13 @@ type Template interface {
 	Location() string // Disk location
 }
 
+// PartialTemplate is implemented by templates which can render one of the
+// templates they define on their own, see Controller.RenderPartial.
+type PartialTemplate interface {
+	// Partial returns the template defined as name, or nil if there is none.
+	Partial(name string) Template
+}
+
 var invalidSlugPattern = regexp.MustCompile(`[^a-z0-9 _-]`)
 var whiteSpacePattern = regexp.MustCompile(`\s+`)
 
@@ -270,7 +277,8 @@ func (loader *TemplateLoader) WatchDir(info os.FileInfo) bool {
 }
 
 // WatchFile returns true of file doesn't start with . (dot)
-// otherwise false
+// otherwise false. Every such file in the template paths is loaded as a
+// template, whatever its extension, e.g. .html, .txt or .xml.
 func (loader *TemplateLoader) WatchFile(basename string) bool {
 	// Watch all files, except the ones starting with a dot.
 	return !strings.HasPrefix(basename, ".")
@@ -329,6 +337,28 @@ func (loader *TemplateLoader) TemplateLang(name, lang string) (tmpl Template, er
 
 	return
 }
+// RenderTemplate renders the named template with the given arguments into a
+// string. It does not depend on a request, so it can be used to generate
+// content such as emails. If args is a view args map containing a locale, the
+// localized version of the template is preferred.
+func (loader *TemplateLoader) RenderTemplate(name string, args interface{}) (string, error) {
+	lang := ""
+	if viewArgs, ok := args.(map[string]interface{}); ok {
+		lang, _ = viewArgs[CurrentLocaleViewArg].(string)
+	}
+
+	tmpl, err := loader.TemplateLang(name, lang)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err = tmpl.Render(&b, args); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
 func (loader *TemplateLoader) templateLoad(name string) (tmpl Template) {
 	if t,found := loader.TemplateMap[name];!found && t != nil {
 		tmpl = t
@@ -22,6 +22,16 @@ func (gotmpl GoTemplate) Render(wr io.Writer, arg interface{}) error {
 	return gotmpl.Execute(wr, arg)
 }
 
+// Partial returns the template of a {{define name}} block, rendered with the
+// same functions as the template.
+func (gotmpl GoTemplate) Partial(name string) Template {
+	tpl := gotmpl.Lookup(name)
+	if tpl == nil {
+		return nil
+	}
+	return &GoTemplate{Template: tpl, engine: gotmpl.engine, TemplateView: gotmpl.TemplateView}
+}
+
 type GoEngine struct {
 	loader      *TemplateLoader
 	templateSet *template.Template
@@ -81,7 +91,7 @@ func (engine *GoEngine) Event(action int, i interface{}) {
 	if action == TEMPLATE_REFRESH_REQUESTED {
 		// At this point all the templates have been passed into the
 		engine.templatesBylowerName = map[string]*GoTemplate{}
-		engine.templateSet = template.New("__root__").Funcs(TemplateFuncs)
+		engine.templateSet = template.New("__root__").Funcs(templateFuncMap())
 		// Check to see what should be used for case sensitivity
 		engine.CaseInsensitiveMode = Config.StringDefault("go.template.path", "lower") != "case"
 	}
@@ -102,7 +112,7 @@ func init() {
 
 		return &GoEngine{
 			loader:               loader,
-			templateSet:          template.New("__root__").Funcs(TemplateFuncs),
+			templateSet:          template.New("__root__").Funcs(templateFuncMap()),
 			templatesBylowerName: map[string]*GoTemplate{},
 			splitDelims:          splitDelims,
 		}, nil
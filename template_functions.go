@@ -6,15 +6,22 @@ import (
 	"html/template"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 	"bytes"
 	"errors"
 )
 
 var (
+	// Guards TemplateFuncs against registrations during a template refresh
+	templateFuncsLock sync.RWMutex
+
 	// The functions available for use in the templates.
+	// Add functions with RegisterTemplateFunc rather than to the map directly.
 	TemplateFuncs = map[string]interface{}{
-		"url": ReverseURL,
+		"url":     ReverseURL,
+		"reverse": ReverseURL,
+		"asset":   AssetURL,
 		"set": func(viewArgs map[string]interface{}, key string, value interface{}) template.JS {
 			viewArgs[key] = value
 			return template.JS("")
@@ -184,6 +191,34 @@ var (
 		},
 	}
 )
+// RegisterTemplateFunc adds a function for use in the templates, which are
+// parsed with it from their next refresh on. It fails if the name is taken,
+// so built in functions can not be replaced by accident.
+func RegisterTemplateFunc(name string, fn interface{}) error {
+	if fn == nil || reflect.TypeOf(fn).Kind() != reflect.Func {
+		return fmt.Errorf("template func %s is not a function", name)
+	}
+
+	templateFuncsLock.Lock()
+	defer templateFuncsLock.Unlock()
+	if _, found := TemplateFuncs[name]; found {
+		return fmt.Errorf("template func %s is already registered", name)
+	}
+	TemplateFuncs[name] = fn
+	return nil
+}
+
+// templateFuncMap returns a copy of the TemplateFuncs to parse templates with.
+func templateFuncMap() template.FuncMap {
+	templateFuncsLock.RLock()
+	defer templateFuncsLock.RUnlock()
+	funcs := make(template.FuncMap, len(TemplateFuncs))
+	for name, fn := range TemplateFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
 /////////////////////
 // Template functions
 /////////////////////
@@ -195,7 +230,10 @@ func ReverseURL(args ...interface{}) (template.URL, error) {
 		return "", errors.New("no arguments provided to reverse route")
 	}
 
-	action := args[0].(string)
+	action, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("reversing %v: expected the action as a string", args[0])
+	}
 	if action == "Root" {
 		return template.URL(AppRoot), nil
 	}
@@ -231,7 +269,11 @@ func ReverseURL(args ...interface{}) (template.URL, error) {
 		Unbind(argsByName, methodType.Args[i + fixedParams].Name, argValue)
 	}
 
-	return template.URL(MainRouter.Reverse(args[0].(string), argsByName).URL), nil
+	actionDefinition := MainRouter.Reverse(action, argsByName)
+	if actionDefinition == nil {
+		return "", fmt.Errorf("reversing %s: no route found", action)
+	}
+	return template.URL(actionDefinition.URL), nil
 }
 
 func Slug(text string) string {
@@ -0,0 +1,195 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplateToString(t *testing.T) {
+	startFakeBookingApp()
+
+	output, err := MainTemplateLoader.RenderTemplate("hotels/show.html", map[string]interface{}{
+		"title": "Booking confirmation",
+		"hotel": &Hotel{3, "A Hotel", "300 Main St.", "New York", "NY", "10010", "USA", 300},
+	})
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %s", err)
+	}
+	if !strings.Contains(output, "<title>Booking confirmation</title>") {
+		t.Errorf("Failed to find title in rendered template:\n%s", output)
+	}
+	if !strings.Contains(output, "300 Main St.") {
+		t.Errorf("Failed to find hotel address in rendered template:\n%s", output)
+	}
+
+	if _, err = MainTemplateLoader.RenderTemplate("hotels/missing.html", nil); err == nil {
+		t.Error("Expected an error rendering a missing template")
+	}
+}
+
+func TestTextTemplate(t *testing.T) {
+	startFakeBookingApp()
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.ViewArgs["hotel"] = &Hotel{3, "A Hotel", "300 Main St.", "New York", "NY", "10010", "USA", 300}
+	c.RenderTemplate("hotels/confirmation.txt").Apply(c.Request, c.Response)
+
+	eq(t, "status", resp.Code, http.StatusOK)
+	eq(t, "content type", resp.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	eq(t, "body", resp.Body.String(), "Booking confirmed: A Hotel\n")
+}
+
+func TestRegisterTemplateFunc(t *testing.T) {
+	defer func() {
+		templateFuncsLock.Lock()
+		delete(TemplateFuncs, "shout")
+		templateFuncsLock.Unlock()
+	}()
+	eq(t, "registration", RegisterTemplateFunc("shout", strings.ToUpper), nil)
+	if err := RegisterTemplateFunc("shout", strings.ToLower); err == nil {
+		t.Error("Expected an error registering a func twice")
+	}
+	if err := RegisterTemplateFunc("url", strings.ToLower); err == nil {
+		t.Error("Expected an error replacing a built in func")
+	}
+	if err := RegisterTemplateFunc("upper", "not a func"); err == nil {
+		t.Error("Expected an error registering a non-function")
+	}
+
+	// The func is available after every refresh
+	for i := 0; i < 2; i++ {
+		startFakeBookingApp()
+		tmpl, err := MainTemplateLoader.Template("hotels/confirmation.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotmpl, _ := tmpl.(*GoTemplate)
+		shouted, err := gotmpl.New("shouted").Parse(`{{shout "hello"}}`)
+		if err != nil {
+			t.Fatalf("Failed to parse with the registered func: %s", err)
+		}
+		var b bytes.Buffer
+		if err = shouted.Execute(&b, nil); err != nil {
+			t.Fatal(err)
+		}
+		eq(t, "registered func output", b.String(), "HELLO")
+	}
+}
+
+func TestTemplateReverseURL(t *testing.T) {
+	startFakeBookingApp()
+
+	tmpl, err := template.New("links").Funcs(TemplateFuncs).Parse(
+		`<a href="{{url "Hotels.Show" 3}}">{{reverse "Hotels.Book" "a b/c"}}</a>`)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %s", err)
+	}
+	var b bytes.Buffer
+	if err = tmpl.Execute(&b, nil); err != nil {
+		t.Fatalf("Failed to execute template: %s", err)
+	}
+	eq(t, "urls", `<a href="/hotels/3">/hotels/a%20b%2Fc/booking</a>`, b.String())
+
+	tmpl, _ = template.New("missing").Funcs(TemplateFuncs).Parse(`{{url "Hotels.Missing"}}`)
+	if err = tmpl.Execute(&b, nil); err == nil {
+		t.Error("Expected an error reversing a missing action")
+	}
+}
+
+func TestTemplateContextFunc(t *testing.T) {
+	startFakeBookingApp()
+	TemplateContextFunc(func(c *Controller) map[string]interface{} {
+		return map[string]interface{}{"hotel": &Hotel{Name: "Context Hotel"}}
+	})
+	defer func() { templateContextFuncs = nil }()
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.RenderTemplate("hotels/confirmation.txt").Apply(c.Request, c.Response)
+	eq(t, "context body", resp.Body.String(), "Booking confirmed: Context Hotel\n")
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.ViewArgs["hotel"] = &Hotel{Name: "A Hotel"}
+	c.RenderTemplate("hotels/confirmation.txt").Apply(c.Request, c.Response)
+	eq(t, "action body", resp.Body.String(), "Booking confirmed: A Hotel\n")
+}
+
+// Test that a controller's template.layout setting wins over the global one,
+// and that RenderPartial leaves the layout out.
+func TestControllerLayout(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("template.layout", "layout.txt")
+	Config.SetOption("template.layout.Admin", "admin/layout.txt")
+	defer Config.SetOption("template.layout", "")
+	defer Config.SetOption("template.layout.Admin", "")
+
+	render := func(name string) string {
+		resp := httptest.NewRecorder()
+		c := NewController(NewRequest(showRequest), NewResponse(resp))
+		c.Name = name
+		c.ViewArgs["title"] = "Bookings"
+		c.ViewArgs["hotel"] = &Hotel{Name: "A Hotel"}
+		c.RenderTemplate("hotels/confirmation.txt").Apply(c.Request, c.Response)
+		return resp.Body.String()
+	}
+	eq(t, "public layout", render("Hotels"), "Public: Booking confirmed: A Hotel\n")
+	eq(t, "admin layout", render("Admin"), "Admin Bookings: Booking confirmed: A Hotel\n")
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.Layout = "admin/layout.txt"
+	c.ViewArgs["hotel"] = &Hotel{Name: "A Hotel"}
+	c.RenderTemplate("hotels/show.txt").Apply(c.Request, c.Response)
+	eq(t, "field layout", resp.Body.String(), "Admin : : A Hotel\n")
+
+	resp = httptest.NewRecorder()
+	c = NewController(NewRequest(showRequest), NewResponse(resp))
+	c.Layout = "missing.html"
+	c.ViewArgs["hotel"] = &Hotel{Name: "A Hotel"}
+	c.RenderPartial("hotels/partial.html", "hotels/partial/details").Apply(c.Request, c.Response)
+	eq(t, "partial status", resp.Code, http.StatusOK)
+	if !strings.HasPrefix(strings.TrimSpace(resp.Body.String()), `<div class="hotel">`) {
+		t.Errorf("Expected the partial without a layout, got %q", resp.Body.String())
+	}
+}
+
+// flushProbe records what the client had been sent when it is rendered.
+type flushProbe struct {
+	resp    *httptest.ResponseRecorder
+	flushed *string
+}
+
+func (p flushProbe) String() string {
+	if p.resp.Flushed {
+		*p.flushed = p.resp.Body.String()
+	}
+	return "Bookings"
+}
+
+// Test that RenderStream flushes the layout before the content is rendered.
+func TestRenderStream(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("template.layout", "layout.txt")
+	defer Config.SetOption("template.layout", "")
+
+	var flushed string
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	c.ViewArgs["title"] = flushProbe{resp, &flushed}
+	c.ViewArgs["hotel"] = &Hotel{Name: "A Hotel"}
+	c.RenderStream("hotels/show.txt").Apply(c.Request, c.Response)
+
+	eq(t, "flushed before the content", flushed, "Public: ")
+	eq(t, "status", resp.Code, http.StatusOK)
+	eq(t, "body", resp.Body.String(), "Public: Bookings: A Hotel\n")
+	eq(t, "content length", resp.Header().Get("Content-Length"), "")
+}
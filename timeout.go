@@ -0,0 +1,66 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const timeoutCancelArg = "_revel_timeout_cancel"
+
+func init() {
+	// Release the deadline of the TimeoutFilter once the response is written,
+	// the result may still be reading from the services given the context
+	OnRequest(func(c *Controller) func() {
+		return func() {
+			if cancel, ok := c.Args[timeoutCancelArg].(context.CancelFunc); ok {
+				cancel()
+			}
+		}
+	})
+}
+
+// TimeoutResult makes the result of a request which exceeded its deadline,
+// see TimeoutFilter. Replace it for a branded page, or a JSON error for the
+// APIs. The response status is set to 503 beforehand, which it may change.
+var TimeoutResult = func(c *Controller) Result {
+	return c.Abort(http.StatusServiceUnavailable, "The request timed out")
+}
+
+// TimeoutFilter gives the request a deadline of http.timeout.request seconds
+// (none by default) on its context, which actions can check with
+// Controller.Deadline, or pass on to the database and the services they call.
+// The context lasts until the response is written, so that a result streaming
+// from such a service, e.g. RenderReader, may still read it. If the chain returns after the deadline, its result is replaced by the one
+// of TimeoutResult, with a 503 Service Unavailable status. Add it after the
+// RouterFilter, e.g.
+//
+//	revel.Filters = []revel.Filter{
+//		revel.PanicFilter,
+//		revel.RouterFilter,
+//		revel.TimeoutFilter,
+//		...
+//	}
+func TimeoutFilter(c *Controller, fc []Filter) {
+	timeout := time.Duration(Config.IntDefault("http.timeout.request", 0)) * time.Second
+	if timeout <= 0 {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	c.Args[timeoutCancelArg] = cancel
+	c.Request.Request = c.Request.WithContext(ctx)
+
+	fc[0](c, fc[1:])
+
+	if ctx.Err() == context.DeadlineExceeded {
+		WARN.Printf("%s %s exceeded http.timeout.request of %s", c.Request.Method, c.Request.URL.Path, timeout)
+		c.Response.Status = http.StatusServiceUnavailable
+		c.Result = TimeoutResult(c)
+	}
+}
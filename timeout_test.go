@@ -0,0 +1,115 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutFilterDeadline(t *testing.T) {
+	startFakeBookingApp()
+	var (
+		deadline time.Time
+		found    bool
+	)
+	action := func(c *Controller, _ []Filter) {
+		deadline, found = c.Deadline()
+		c.Result = c.RenderText("ok")
+	}
+
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	TimeoutFilter(c, []Filter{action})
+	eq(t, "deadline without timeout", found, false)
+
+	Config.SetOption("http.timeout.request", "30")
+	defer Config.SetOption("http.timeout.request", "0")
+	start := time.Now()
+	c = NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	TimeoutFilter(c, []Filter{action})
+	eq(t, "deadline with timeout", found, true)
+	if remaining := deadline.Sub(start); remaining < 30*time.Second || remaining > 31*time.Second {
+		t.Errorf("Expected a deadline 30s ahead, got %s", remaining)
+	}
+	eq(t, "status in time", c.Response.Status, http.StatusOK)
+}
+
+func TestTimeoutFilterExpired(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("http.timeout.request", "1")
+	defer Config.SetOption("http.timeout.request", "0")
+
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	TimeoutFilter(c, []Filter{func(c *Controller, _ []Filter) {
+		<-c.Request.Context().Done()
+		c.Result = c.RenderText("too late")
+	}})
+	eq(t, "status", c.Response.Status, http.StatusServiceUnavailable)
+	if _, ok := c.Result.(ErrorResult); !ok {
+		t.Errorf("Expected an error result, got %#v", c.Result)
+	}
+}
+
+func TestTimeoutResult(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("http.timeout.request", "1")
+	defer Config.SetOption("http.timeout.request", "0")
+	defer func(result func(*Controller) Result) { TimeoutResult = result }(TimeoutResult)
+	TimeoutResult = func(c *Controller) Result {
+		return c.RenderJSON(map[string]string{"error": "timeout"})
+	}
+
+	resp := httptest.NewRecorder()
+	c := NewController(NewRequest(showRequest), NewResponse(resp))
+	TimeoutFilter(c, []Filter{func(c *Controller, _ []Filter) {
+		<-c.Request.Context().Done()
+		c.Result = c.RenderText("too late")
+	}})
+	c.Result.Apply(c.Request, c.Response)
+	eq(t, "status", resp.Code, http.StatusServiceUnavailable)
+	eq(t, "content type", resp.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	eq(t, "body", resp.Body.String(), `{"error":"timeout"}`)
+}
+
+// contextReader reads as long as its context is live.
+type contextReader struct {
+	ctx  context.Context
+	read bool
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if r.read {
+		return 0, io.EOF
+	}
+	r.read = true
+	return copy(p, "streamed"), nil
+}
+
+// Test that a result streaming from the context is applied before the
+// context is canceled.
+func TestTimeoutFilterStreamedResult(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("http.timeout.request", "30")
+	defer Config.SetOption("http.timeout.request", "0")
+	filters := Filters
+	defer func() { Filters = filters }()
+
+	var ctx context.Context
+	Filters = []Filter{TimeoutFilter, func(c *Controller, _ []Filter) {
+		ctx = c.Request.Context()
+		c.Result = c.RenderReader("text/plain", &contextReader{ctx: ctx})
+	}}
+	resp := httptest.NewRecorder()
+	handle(resp, httptest.NewRequest("GET", "/stream", nil))
+	eq(t, "body", resp.Body.String(), "streamed")
+	eq(t, "context once written", ctx.Err(), context.Canceled)
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// FilterTiming is the time spent in a single filter of a request, excluding
+// the time spent in the filters after it in the chain.
+type FilterTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+const (
+	traceArg  = "_revel_trace"
+	tracerArg = "_revel_tracer"
+)
+
+// filterTracer times the filters of a traced request. The chain may be
+// replaced partway, by the FilterConfiguringFilter.
+type filterTracer struct {
+	filters   []Filter
+	inclusive []time.Duration // Time spent in each filter and those after it
+}
+
+// wrap replaces the filters from index on with chain and returns the timed
+// wrappers of the new filters.
+func (t *filterTracer) wrap(index int, chain []Filter) []Filter {
+	t.filters = append(t.filters[:index:index], chain...)
+	t.inclusive = make([]time.Duration, len(t.filters)+1)
+	traced := make([]Filter, len(chain))
+	for i := range chain {
+		i, filter := index+i, chain[i]
+		traced[i-index] = func(c *Controller, chain []Filter) {
+			start := time.Now()
+			filter(c, chain)
+			t.inclusive[i] = time.Since(start)
+		}
+	}
+	return traced
+}
+
+// traceOverrideChain returns the override chain taking the place of the rest
+// of the chain, remaining, timed if the request is traced.
+func traceOverrideChain(c *Controller, remaining, chain []Filter) []Filter {
+	t, ok := c.Args[tracerArg].(*filterTracer)
+	if !ok {
+		return chain
+	}
+	return t.wrap(len(t.filters)-len(remaining), chain)
+}
+
+// TraceFilter records the time spent in each of the following filters (and
+// the action, through the ActionInvoker) and logs the breakdown of every
+// request to the INFO log. The filters of an override chain set up by
+// FilterConfiguringFilter are timed in place of the default ones. It does
+// nothing unless "trace=true" is set in app.conf. To trace all of the
+// filters, make it the first one:
+//
+//	revel.Filters = append([]revel.Filter{revel.TraceFilter}, revel.Filters...)
+func TraceFilter(c *Controller, fc []Filter) {
+	if !Config.BoolDefault("trace", false) {
+		fc[0](c, fc[1:])
+		return
+	}
+
+	t := &filterTracer{}
+	traced := t.wrap(0, fc)
+	c.Args[tracerArg] = t
+	traced[0](c, traced[1:])
+	delete(c.Args, tracerArg)
+
+	timings := make([]FilterTiming, len(t.filters))
+	var b bytes.Buffer
+	for i, filter := range t.filters {
+		timings[i] = FilterTiming{filterName(filter), t.inclusive[i] - t.inclusive[i+1]}
+		fmt.Fprintf(&b, " %s=%v", timings[i].Name, timings[i].Duration)
+	}
+	c.Args[traceArg] = timings
+	INFO.Printf("Trace %s %s:%s", c.Request.Method, c.Request.URL.Path, b.String())
+}
+
+// FilterTimings returns the timings recorded by the TraceFilter for the
+// request, or nil if it was not traced.
+func FilterTimings(c *Controller) []FilterTiming {
+	timings, _ := c.Args[traceArg].([]FilterTiming)
+	return timings
+}
+
+// The names given to filters with NamedFilter, by function pointer.
+var (
+	filterNames     = map[uintptr]string{}
+	filterNamesLock sync.RWMutex
+)
+
+// NamedFilter gives the filter a name to be listed by FilterNames and the
+// TraceFilter, instead of the name of its function, and returns it. This is
+// handy for filters made by closures, which are named after the function
+// creating them, e.g. myapp.AuthFilter.func1. All the closures of the same
+// function literal share the name.
+func NamedFilter(name string, filter Filter) Filter {
+	filterNamesLock.Lock()
+	defer filterNamesLock.Unlock()
+	filterNames[reflect.ValueOf(filter).Pointer()] = name
+	return filter
+}
+
+// FilterNames returns the names of the global Filters in order, e.g. to
+// check the chain at startup:
+//
+//	revel.OnAppStart(func() { revel.INFO.Println("Filters:", revel.FilterNames()) })
+func FilterNames() []string {
+	names := make([]string, len(Filters))
+	for i, filter := range Filters {
+		names[i] = filterName(filter)
+	}
+	return names
+}
+
+// filterName returns the name of the filter function, e.g. revel.RouterFilter.
+func filterName(filter Filter) string {
+	filterNamesLock.RLock()
+	name, ok := filterNames[reflect.ValueOf(filter).Pointer()]
+	filterNamesLock.RUnlock()
+	if ok {
+		return name
+	}
+	if fn := runtime.FuncForPC(reflect.ValueOf(filter).Pointer()); fn != nil {
+		return path.Base(fn.Name())
+	}
+	return "unknown"
+}
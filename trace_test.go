@@ -0,0 +1,80 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sleepingFilter(c *Controller, fc []Filter) {
+	time.Sleep(20 * time.Millisecond)
+	fc[0](c, fc[1:])
+}
+
+func TestTraceFilter(t *testing.T) {
+	startFakeBookingApp()
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+
+	TraceFilter(c, []Filter{sleepingFilter, NilFilter})
+	if FilterTimings(c) != nil {
+		t.Error("Expected no timings unless trace is enabled")
+	}
+
+	Config.SetOption("trace", "true")
+	TraceFilter(c, []Filter{sleepingFilter, NilFilter})
+	timings := FilterTimings(c)
+	if len(timings) != 2 {
+		t.Fatalf("Expected 2 timings, got %v", timings)
+	}
+	eq(t, "filter name", "revel.sleepingFilter", timings[0].Name)
+	if timings[0].Duration < 20*time.Millisecond {
+		t.Errorf("Expected the sleeping filter to take at least 20ms, got %s", timings[0].Duration)
+	}
+	if timings[1].Duration >= 20*time.Millisecond {
+		t.Errorf("Expected the nil filter to take less than 20ms, got %s", timings[1].Duration)
+	}
+}
+
+func TestTraceFilterOverrideChain(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("trace", "true")
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	c.Name, c.Action = "Hotels", "Hotels.Show"
+	filterOverrides[c.Action] = []Filter{sleepingFilter, NilFilter}
+	defer delete(filterOverrides, c.Action)
+
+	TraceFilter(c, []Filter{FilterConfiguringFilter, ActionInvoker})
+	timings := FilterTimings(c)
+	if len(timings) != 3 {
+		t.Fatalf("Expected 3 timings, got %v", timings)
+	}
+	eq(t, "override filter name", timings[1].Name, "revel.sleepingFilter")
+	if timings[0].Duration >= 20*time.Millisecond {
+		t.Errorf("Expected the override chain to be timed on its own, got %s", timings[0].Duration)
+	}
+	if timings[1].Duration < 20*time.Millisecond {
+		t.Errorf("Expected the sleeping filter to take at least 20ms, got %s", timings[1].Duration)
+	}
+}
+
+func TestFilterNames(t *testing.T) {
+	startFakeBookingApp()
+	eq(t, "default filters", strings.Join(FilterNames(), ","), "revel.PanicFilter,revel.RouterFilter,"+
+		"revel.FilterConfiguringFilter,revel.ConsumesFilter,revel.DecompressRequestFilter,revel.ParamsFilter,"+
+		"revel.SessionFilter,revel.FlashFilter,revel.ValidationFilter,revel.I18nFilter,"+
+		"revel.InterceptorFilter,revel.CompressFilter,revel.ActionInvoker")
+
+	filters := Filters
+	defer func() { Filters = filters }()
+	Filters = []Filter{
+		PanicFilter,
+		NamedFilter("auth", func(c *Controller, fc []Filter) { fc[0](c, fc[1:]) }),
+		ActionInvoker,
+	}
+	eq(t, "named filter", strings.Join(FilterNames(), ","), "revel.PanicFilter,auth,revel.ActionInvoker")
+}
@@ -15,6 +15,7 @@ import (
 // ValidationError simple struct to store the Message & Key of a validation error
 type ValidationError struct {
 	Message, Key string
+	Bind         *BindError // The failed conversion, for an error binding a param
 }
 
 // String returns the Message field of the ValidationError struct.
@@ -12,5 +12,5 @@ const (
 	BuildDate = "2017-07-11"
 
 	// MinimumGoVersion minimum required Go version for Revel
-	MinimumGoVersion = ">= go1.6"
+	MinimumGoVersion = ">= go1.13"
 )
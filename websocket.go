@@ -0,0 +1,178 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// The websocket connections being served, and the open websockets to close
+// on shutdown
+var (
+	websocketConns     sync.WaitGroup
+	openWebsockets     = map[*websocket.Conn]struct{}{}
+	openWebsocketsLock sync.Mutex
+)
+
+// trackWebsocket adds the websocket to the ones closed on shutdown until the
+// returned func is called.
+func trackWebsocket(ws *websocket.Conn) (untrack func()) {
+	openWebsocketsLock.Lock()
+	openWebsockets[ws] = struct{}{}
+	openWebsocketsLock.Unlock()
+	return func() {
+		openWebsocketsLock.Lock()
+		delete(openWebsockets, ws)
+		openWebsocketsLock.Unlock()
+	}
+}
+
+// waitWebsockets waits up to the timeout for the websockets to be closed,
+// then closes the remaining ones and waits for their handlers as long again.
+func waitWebsockets(timeout time.Duration) {
+	closed := make(chan struct{})
+	go func() {
+		websocketConns.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		return
+	case <-time.After(timeout):
+	}
+
+	openWebsocketsLock.Lock()
+	WARN.Printf("Closing %d websockets still open after websocket.shutdown.timeout", len(openWebsockets))
+	for ws := range openWebsockets {
+		_ = ws.Close()
+	}
+	openWebsocketsLock.Unlock()
+	select {
+	case <-closed:
+	case <-time.After(timeout):
+		ERROR.Println("Websocket handlers did not return after their websockets were closed")
+	}
+}
+
+// ErrNoWebsocket is returned by the websocket helpers of the Controller when
+// the request is not a websocket request.
+var ErrNoWebsocket = errors.New("revel: not a websocket request")
+
+// ReceiveJSON reads the next message from the websocket and unmarshals it
+// into v. It returns io.EOF once the socket has been closed by the client.
+func (c *Controller) ReceiveJSON(v interface{}) error {
+	if c.Request.Websocket == nil {
+		return ErrNoWebsocket
+	}
+	return websocket.JSON.Receive(c.Request.Websocket, v)
+}
+
+// SendJSON marshals v and sends it as a text message on the websocket.
+func (c *Controller) SendJSON(v interface{}) error {
+	if c.Request.Websocket == nil {
+		return ErrNoWebsocket
+	}
+	return websocket.JSON.Send(c.Request.Websocket, v)
+}
+
+// ReceiveText reads the next message from the websocket as a string.
+// It returns io.EOF once the socket has been closed by the client.
+func (c *Controller) ReceiveText() (string, error) {
+	if c.Request.Websocket == nil {
+		return "", ErrNoWebsocket
+	}
+	var text string
+	err := websocket.Message.Receive(c.Request.Websocket, &text)
+	return text, err
+}
+
+// SendText sends text as a text message on the websocket.
+func (c *Controller) SendText(text string) error {
+	if c.Request.Websocket == nil {
+		return ErrNoWebsocket
+	}
+	return websocket.Message.Send(c.Request.Websocket, text)
+}
+
+// checkWebsocketOrigin is the handshake of websocket requests. It rejects
+// requests from origins which are not listed in websocket.origins, a comma
+// separated list of origins such as https://example.com, or "*" for any.
+// By default only the origin of the app itself is allowed. Requests without
+// an Origin header do not come from a browser and are accepted.
+func checkWebsocketOrigin(config *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return err
+	}
+	config.Origin = u
+
+	allowed, found := Config.String("websocket.origins")
+	if !found {
+		if strings.EqualFold(u.Host, req.Host) {
+			return nil
+		}
+	}
+	for _, allow := range strings.Split(allowed, ",") {
+		allow = strings.TrimSpace(allow)
+		if allow == "*" || strings.EqualFold(strings.TrimSuffix(allow, "/"), origin) {
+			return nil
+		}
+	}
+	WARN.Printf("Websocket: rejecting %s from origin %s", req.URL.Path, origin)
+	return fmt.Errorf("websocket origin not allowed: %s", origin)
+}
+
+// idleTimeoutResponseWriter hands out connections with an idle timeout when
+// hijacked for a websocket, see websocket.idle.timeout.
+type idleTimeoutResponseWriter struct {
+	http.ResponseWriter
+	timeout time.Duration
+}
+
+func (w *idleTimeoutResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	idleConn := &idleTimeoutConn{conn, w.timeout}
+
+	// Read through the idle connection, after anything already buffered
+	var buffered []byte
+	if n := rw.Reader.Buffered(); n > 0 {
+		buffered, _ = rw.Reader.Peek(n)
+	}
+	rw.Reader = bufio.NewReader(io.MultiReader(bytes.NewReader(buffered), idleConn))
+	return idleConn, rw, nil
+}
+
+// idleTimeoutConn extends the read deadline before every read, so a read
+// fails once the peer has been silent for the timeout.
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
@@ -0,0 +1,337 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The permessage-deflate extension (RFC 7692) accepted when websocket.compress
+// is on. Both ends compress every message on its own, without keeping the
+// compression context between messages, which bounds the memory per socket.
+//
+// The websocket package has no support for extensions, so the messages are
+// compressed and decompressed on the hijacked connection, below its frames.
+const websocketDeflateExtension = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+
+// The largest message decompressed, or frame buffered, on a compressed
+// websocket.
+const websocketMaxMessageSize = 32 << 20 // 32 MB
+
+var (
+	errWebsocketTooLarge = errors.New("revel: websocket message too large")
+
+	// The tail of a flushed deflate stream, which the messages are sent
+	// without, and the final empty block ending the stream of a message
+	deflateTail  = []byte{0x00, 0x00, 0xff, 0xff}
+	deflateFinal = []byte{0x01, 0x00, 0x00, 0xff, 0xff}
+
+	deflateWriters = sync.Pool{New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	}}
+)
+
+// The bits of the websocket frame header, see RFC 6455 section 5.2
+const (
+	websocketFin      = 0x80
+	websocketRsv1     = 0x40 // Set on the first frame of compressed messages
+	websocketMask     = 0x80
+	websocketOpcode   = 0x0f
+	websocketOpText   = 0x1
+	websocketOpBinary = 0x2
+)
+
+// websocketDeflateOffered reports whether the client offers permessage-deflate
+// with parameters the server can accept. The server_max_window_bits parameter
+// is declined, the compressor always uses the largest window.
+func websocketDeflateOffered(header http.Header) bool {
+	for _, value := range header[http.CanonicalHeaderKey("Sec-WebSocket-Extensions")] {
+	offers:
+		for _, offer := range strings.Split(value, ",") {
+			params := strings.Split(offer, ";")
+			if strings.TrimSpace(params[0]) != "permessage-deflate" {
+				continue
+			}
+			for _, param := range params[1:] {
+				name := strings.TrimSpace(strings.SplitN(param, "=", 2)[0])
+				switch name {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				default:
+					continue offers
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// deflateResponseWriter hands out connections compressing the websocket
+// messages, once the handshake has accepted permessage-deflate.
+type deflateResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *deflateResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	deflateConn := &deflateConn{Conn: conn, source: rw.Reader}
+	return deflateConn, bufio.NewReadWriter(bufio.NewReader(deflateConn), bufio.NewWriter(deflateConn)), nil
+}
+
+// deflateConn decompresses the messages read from the client and compresses
+// those written by the server, after the handshake response. The frames of
+// the other messages pass through as they are. A rejected handshake turns it
+// into a plain connection.
+type deflateConn struct {
+	net.Conn
+	source *bufio.Reader // Reads the client's frames, after the buffered ones
+
+	// The frames to be read, and the compressed message being received
+	read      []byte
+	message   []byte
+	messageOp byte
+
+	// The start of the frame, or of the handshake response, being written
+	written     []byte
+	handshaken  bool
+	passthrough bool
+}
+
+func (c *deflateConn) Read(b []byte) (int, error) {
+	if c.passthrough {
+		return c.source.Read(b)
+	}
+	for len(c.read) == 0 {
+		if err := c.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, c.read)
+	c.read = c.read[n:]
+	return n, nil
+}
+
+// readFrame reads the next frame from the client. It is queued to be read as
+// it is, unless it belongs to a compressed message, which is queued once
+// complete as a single decompressed frame.
+func (c *deflateConn) readFrame() error {
+	frame, payload, err := readWebsocketFrame(c.source)
+	if err != nil {
+		return err
+	}
+	switch op := frame[0] & websocketOpcode; {
+	case op == 0 && c.message != nil:
+	case frame[0]&websocketRsv1 != 0 && (op == websocketOpText || op == websocketOpBinary):
+		c.message, c.messageOp = []byte{}, op
+	default:
+		// Control frames may come between the frames of a message
+		c.read = append(c.read, frame...)
+		return nil
+	}
+
+	if len(c.message)+len(payload) > websocketMaxMessageSize {
+		return errWebsocketTooLarge
+	}
+	c.message = append(c.message, payload...)
+	if frame[0]&websocketFin == 0 {
+		return nil
+	}
+	message, err := inflateMessage(c.message)
+	c.message = nil
+	if err != nil {
+		return err
+	}
+	// Masked as the websocket package expects of a client, with a zero key
+	c.read = appendWebsocketFrame(c.read, websocketFin|c.messageOp, message, true)
+	return nil
+}
+
+func (c *deflateConn) Write(b []byte) (int, error) {
+	if c.passthrough {
+		return c.Conn.Write(b)
+	}
+	c.written = append(c.written, b...)
+
+	if !c.handshaken {
+		end := bytes.Index(c.written, []byte("\r\n\r\n"))
+		if end < 0 {
+			return len(b), nil
+		}
+		// Only an accepted handshake is followed by frames
+		c.handshaken = true
+		c.passthrough = !bytes.HasPrefix(c.written, []byte("HTTP/1.1 101 "))
+		if c.passthrough {
+			end = len(c.written) - 4
+		}
+		if _, err := c.Conn.Write(c.written[:end+4]); err != nil {
+			return 0, err
+		}
+		c.written = c.written[end+4:]
+	}
+
+	for {
+		frame, payload, ok := parseWebsocketFrame(c.written)
+		if !ok {
+			break
+		}
+		c.written = c.written[len(frame):]
+		// The single frame messages are compressed when it makes them smaller
+		switch frame[0] {
+		case websocketFin | websocketOpText, websocketFin | websocketOpBinary:
+			if frame[1]&websocketMask == 0 {
+				if compressed := deflateMessage(payload); len(compressed) < len(payload) {
+					frame = appendWebsocketFrame(nil, frame[0]|websocketRsv1, compressed, false)
+				}
+			}
+		}
+		if _, err := c.Conn.Write(frame); err != nil {
+			return 0, err
+		}
+	}
+	if len(c.written) == 0 {
+		c.written = nil
+	} else if len(c.written) > websocketMaxMessageSize {
+		return 0, errWebsocketTooLarge
+	}
+	return len(b), nil
+}
+
+// readWebsocketFrame reads a frame, returning it as it was sent and its
+// unmasked payload.
+func readWebsocketFrame(r *bufio.Reader) (frame, payload []byte, err error) {
+	frame = make([]byte, 2, 14)
+	if _, err = io.ReadFull(r, frame); err != nil {
+		return nil, nil, err
+	}
+	headerLen := websocketHeaderLen(frame)
+	frame = frame[:headerLen]
+	if _, err = io.ReadFull(r, frame[2:]); err != nil {
+		return nil, nil, err
+	}
+	length := websocketPayloadLen(frame)
+	if length > websocketMaxMessageSize {
+		return nil, nil, errWebsocketTooLarge
+	}
+	frame = append(frame, make([]byte, length)...)
+	if _, err = io.ReadFull(r, frame[headerLen:]); err != nil {
+		return nil, nil, err
+	}
+	return frame, unmaskWebsocketPayload(frame, headerLen), nil
+}
+
+// parseWebsocketFrame returns the first frame of b and its unmasked payload,
+// if b holds all of it.
+func parseWebsocketFrame(b []byte) (frame, payload []byte, ok bool) {
+	if len(b) < 2 || len(b) < websocketHeaderLen(b) {
+		return nil, nil, false
+	}
+	headerLen := websocketHeaderLen(b)
+	length := websocketPayloadLen(b[:headerLen])
+	if uint64(len(b)-headerLen) < length {
+		return nil, nil, false
+	}
+	frame = b[:headerLen+int(length)]
+	return frame, unmaskWebsocketPayload(frame, headerLen), true
+}
+
+// websocketHeaderLen returns the length of the header starting with the
+// two bytes of b.
+func websocketHeaderLen(b []byte) int {
+	n := 2
+	switch b[1] &^ websocketMask {
+	case 126:
+		n += 2
+	case 127:
+		n += 8
+	}
+	if b[1]&websocketMask != 0 {
+		n += 4
+	}
+	return n
+}
+
+// websocketPayloadLen returns the payload length of the header.
+func websocketPayloadLen(header []byte) uint64 {
+	switch length := header[1] &^ websocketMask; length {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(header[2:]))
+	case 127:
+		return binary.BigEndian.Uint64(header[2:])
+	default:
+		return uint64(length)
+	}
+}
+
+// unmaskWebsocketPayload returns a copy of the payload of the frame, unmasked
+// if it is masked.
+func unmaskWebsocketPayload(frame []byte, headerLen int) []byte {
+	payload := append([]byte(nil), frame[headerLen:]...)
+	if frame[1]&websocketMask != 0 {
+		key := frame[headerLen-4 : headerLen]
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return payload
+}
+
+// appendWebsocketFrame appends a frame with the first header byte and the
+// payload, masked with a zero key if desired, which leaves it as it is.
+func appendWebsocketFrame(b []byte, first byte, payload []byte, masked bool) []byte {
+	var mask byte
+	if masked {
+		mask = websocketMask
+	}
+	switch length := len(payload); {
+	case length < 126:
+		b = append(b, first, mask|byte(length))
+	case length < 1<<16:
+		b = append(b, first, mask|126, 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(length))
+	default:
+		b = append(b, first, mask|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], uint64(length))
+	}
+	if masked {
+		b = append(b, 0, 0, 0, 0)
+	}
+	return append(b, payload...)
+}
+
+// deflateMessage compresses the payload of a message, see RFC 7692 section 7.2.1.
+func deflateMessage(payload []byte) []byte {
+	var b bytes.Buffer
+	w := deflateWriters.Get().(*flate.Writer)
+	defer deflateWriters.Put(w)
+	w.Reset(&b)
+	_, _ = w.Write(payload)
+	_ = w.Flush()
+	return bytes.TrimSuffix(b.Bytes(), deflateTail)
+}
+
+// inflateMessage decompresses the payload of a message, see RFC 7692 section 7.2.2.
+func inflateMessage(payload []byte) ([]byte, error) {
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail), bytes.NewReader(deflateFinal)))
+	defer r.Close()
+	message, err := ioutil.ReadAll(io.LimitReader(r, websocketMaxMessageSize+1))
+	if err == nil && len(message) > websocketMaxMessageSize {
+		err = errWebsocketTooLarge
+	}
+	return message, err
+}
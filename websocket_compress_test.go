@@ -0,0 +1,147 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebsocketDeflateOffered(t *testing.T) {
+	for offer, expected := range map[string]bool{
+		"":                   false,
+		"permessage-deflate": true,
+		"permessage-deflate; client_max_window_bits":                             true,
+		"x-webkit-deflate-frame, permessage-deflate; client_no_context_takeover": true,
+		"permessage-deflate; server_max_window_bits=10":                          false,
+		"permessage-deflate; server_max_window_bits=10, permessage-deflate":      true,
+	} {
+		header := http.Header{}
+		if offer != "" {
+			header.Set("Sec-WebSocket-Extensions", offer)
+		}
+		eq(t, offer, websocketDeflateOffered(header), expected)
+	}
+}
+
+// dialWebsocket sends a websocket handshake offering the extensions, returning
+// the connection and the handshake response.
+func dialWebsocket(t *testing.T, server *httptest.Server, extensions string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/hotels/stream", nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Extensions", extensions)
+	if err = req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, r, resp
+}
+
+// sendWebsocketFrame sends a frame masked like a client's.
+func sendWebsocketFrame(t *testing.T, conn net.Conn, first byte, payload []byte) {
+	frame := appendWebsocketFrame(nil, first, payload, true)
+	key := frame[len(frame)-len(payload)-4 : len(frame)-len(payload)]
+	copy(key, "\x01\x02\x03\x04")
+	for i := range payload {
+		frame[len(frame)-len(payload)+i] ^= key[i%4]
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebsocketCompress(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("websocket.compress", "true")
+	defer Config.SetOption("websocket.compress", "false")
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+
+	conn, r, resp := dialWebsocket(t, server, "permessage-deflate; client_max_window_bits")
+	defer waitWebsocketActions(t)
+	defer conn.Close()
+	eq(t, "status", resp.StatusCode, http.StatusSwitchingProtocols)
+	eq(t, "extension", resp.Header.Get("Sec-WebSocket-Extensions"), websocketDeflateExtension)
+
+	// A compressed message, in fragments here, is echoed compressed
+	message := strings.Repeat(`{"name":"hotel","city":"New York"}`, 20)
+	compressed := deflateMessage([]byte(message))
+	sendWebsocketFrame(t, conn, websocketRsv1|websocketOpText, compressed[:len(compressed)/2])
+	sendWebsocketFrame(t, conn, websocketFin, compressed[len(compressed)/2:])
+	frame, payload, err := readWebsocketFrame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "compressed reply header", frame[0], byte(websocketFin|websocketRsv1|websocketOpText))
+	if len(payload) >= len(message) {
+		t.Errorf("Expected the reply compressed, got %d bytes for %d", len(payload), len(message))
+	}
+	reply, err := inflateMessage(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "compressed reply", string(reply), message)
+
+	// A message which doesn't compress is sent as it is
+	sendWebsocketFrame(t, conn, websocketFin|websocketOpText, []byte("hi"))
+	frame, payload, err = readWebsocketFrame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "plain reply header", frame[0], byte(websocketFin|websocketOpText))
+	eq(t, "plain reply", string(payload), "hi")
+}
+
+func TestWebsocketCompressNotNegotiated(t *testing.T) {
+	startFakeBookingApp()
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+
+	// Disabled by default
+	conn, _, resp := dialWebsocket(t, server, "permessage-deflate")
+	_ = conn.Close()
+	waitWebsocketActions(t)
+	eq(t, "status", resp.StatusCode, http.StatusSwitchingProtocols)
+	eq(t, "extension when disabled", resp.Header.Get("Sec-WebSocket-Extensions"), "")
+
+	Config.SetOption("websocket.compress", "true")
+	defer Config.SetOption("websocket.compress", "false")
+	conn, _, resp = dialWebsocket(t, server, "x-webkit-deflate-frame")
+	_ = conn.Close()
+	waitWebsocketActions(t)
+	eq(t, "extension not offered", resp.Header.Get("Sec-WebSocket-Extensions"), "")
+
+	// A rejected handshake passes through
+	Config.SetOverride("websocket.origins", "https://app.example.com")
+	defer Config.RemoveOverride("websocket.origins")
+	req, _ := http.NewRequest("GET", server.URL+"/hotels/stream", nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate")
+	req.Header.Set("Origin", "http://evil.example.com")
+	rejected, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rejected.Body.Close()
+	eq(t, "rejected status", rejected.StatusCode, http.StatusForbidden)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// WebsocketConn is a connection of a WebsocketHub, typically the
+// *websocket.Conn of Request.Websocket.
+type WebsocketConn interface {
+	io.Writer
+	io.Closer
+}
+
+// WebsocketHub broadcasts messages to the websockets registered with it, e.g.
+// for a chat:
+//
+//	var room = revel.NewWebsocketHub(0)
+//
+//	func (c Chat) Join(ws *websocket.Conn) revel.Result {
+//		room.Register(ws)
+//		defer room.Unregister(ws)
+//		for {
+//			text, err := c.ReceiveText()
+//			if err != nil {
+//				return nil
+//			}
+//			room.Broadcast([]byte(text))
+//		}
+//	}
+//
+// Every connection has a buffer of messages written to it in the background,
+// so a slow connection doesn't hold the others up. A connection whose buffer
+// is full is dropped: it is unregistered and closed.
+type WebsocketHub struct {
+	bufferSize int
+	lock       sync.Mutex
+	conns      map[WebsocketConn]chan []byte
+}
+
+// NewWebsocketHub returns a hub buffering bufferSize messages for every
+// connection, or 16 if it is zero.
+func NewWebsocketHub(bufferSize int) *WebsocketHub {
+	if bufferSize <= 0 {
+		bufferSize = 16
+	}
+	return &WebsocketHub{
+		bufferSize: bufferSize,
+		conns:      map[WebsocketConn]chan []byte{},
+	}
+}
+
+// Register adds the connection to the hub for it to get the broadcasts.
+func (h *WebsocketHub) Register(conn WebsocketConn) {
+	messages := make(chan []byte, h.bufferSize)
+	h.lock.Lock()
+	if _, found := h.conns[conn]; found {
+		h.lock.Unlock()
+		return
+	}
+	h.conns[conn] = messages
+	h.lock.Unlock()
+
+	go func() {
+		for message := range messages {
+			if _, err := conn.Write(message); err != nil {
+				h.drop(conn)
+				return
+			}
+		}
+	}()
+}
+
+// Unregister removes the connection from the hub, leaving it open.
+func (h *WebsocketHub) Unregister(conn WebsocketConn) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.remove(conn)
+}
+
+// Len returns the number of connections registered.
+func (h *WebsocketHub) Len() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.conns)
+}
+
+// Broadcast queues the message for every connection. The connections which
+// have no room left for it are dropped.
+func (h *WebsocketHub) Broadcast(message []byte) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for conn, messages := range h.conns {
+		select {
+		case messages <- message:
+		default:
+			WARN.Println("Dropping a websocket which does not keep up with the broadcasts")
+			h.remove(conn)
+			closeWebsocketConn(conn)
+		}
+	}
+}
+
+// BroadcastJSON broadcasts v marshalled as JSON.
+func (h *WebsocketHub) BroadcastJSON(v interface{}) error {
+	message, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h.Broadcast(message)
+	return nil
+}
+
+// drop removes and closes a connection which failed.
+func (h *WebsocketHub) drop(conn WebsocketConn) {
+	h.lock.Lock()
+	_, found := h.conns[conn]
+	h.remove(conn)
+	h.lock.Unlock()
+	if found {
+		closeWebsocketConn(conn)
+	}
+}
+
+// remove removes the connection, stopping its writes. Call it with the lock
+// held.
+func (h *WebsocketHub) remove(conn WebsocketConn) {
+	if messages, found := h.conns[conn]; found {
+		delete(h.conns, conn)
+		close(messages)
+	}
+}
+
+// closeWebsocketConn closes the connection without waiting for a write it is
+// stuck in, which a *websocket.Conn would do.
+func closeWebsocketConn(conn WebsocketConn) {
+	if deadliner, ok := conn.(interface {
+		SetWriteDeadline(time.Time) error
+	}); ok {
+		_ = deadliner.SetWriteDeadline(time.Now())
+	}
+	go func() {
+		_ = conn.Close()
+	}()
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeWebsocketConn is a connection of a hub, which stalls in every write if
+// it is stalled.
+type fakeWebsocketConn struct {
+	stalled  bool
+	messages chan string
+	closed   chan struct{}
+}
+
+func newFakeWebsocketConn(stalled bool) *fakeWebsocketConn {
+	return &fakeWebsocketConn{stalled, make(chan string, 100), make(chan struct{})}
+}
+
+func (c *fakeWebsocketConn) Write(p []byte) (int, error) {
+	if c.stalled {
+		<-c.closed
+		return 0, errors.New("closed")
+	}
+	c.messages <- string(p)
+	return len(p), nil
+}
+
+func (c *fakeWebsocketConn) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func (c *fakeWebsocketConn) receive(t *testing.T) string {
+	select {
+	case message := <-c.messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("The broadcast did not arrive")
+		return ""
+	}
+}
+
+func TestWebsocketHub(t *testing.T) {
+	hub := NewWebsocketHub(2)
+	first, second, stalled := newFakeWebsocketConn(false), newFakeWebsocketConn(false), newFakeWebsocketConn(true)
+	hub.Register(first)
+	hub.Register(second)
+	hub.Register(stalled)
+	eq(t, "registered", hub.Len(), 3)
+
+	// The stalled connection can't take more than its buffer and the message
+	// it is stuck writing
+	for i := 0; i < 5; i++ {
+		message := fmt.Sprint("message ", i)
+		hub.Broadcast([]byte(message))
+		eq(t, "first connection", first.receive(t), message)
+		eq(t, "second connection", second.receive(t), message)
+	}
+
+	select {
+	case <-stalled.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the stalled connection to be closed")
+	}
+	eq(t, "live connections", hub.Len(), 2)
+
+	hub.Unregister(second)
+	if err := hub.BroadcastJSON(map[string]int{"count": 1}); err != nil {
+		t.Fatal(err)
+	}
+	eq(t, "JSON broadcast", first.receive(t), `{"count":1}`)
+	select {
+	case message := <-second.messages:
+		t.Errorf("Expected no broadcast to the unregistered connection, got %q", message)
+	case <-time.After(10 * time.Millisecond):
+	}
+	eq(t, "after unregistering", hub.Len(), 1)
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2012-2017 The Revel Framework Authors, All rights reserved.
+// Revel Framework source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package revel
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Echo the messages received, the action of the websocket tests.
+func (c Hotels) Stream(ws *websocket.Conn) Result {
+	var msg string
+	for websocket.Message.Receive(ws, &msg) == nil {
+		if websocket.Message.Send(ws, msg) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// waitWebsocketActions waits for the websocket actions to return, so that
+// they don't outlive the test on their hijacked connections.
+func waitWebsocketActions(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		websocketConns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Websocket actions still running")
+	}
+}
+
+type websocketMessage struct {
+	Name  string
+	Count int
+}
+
+func TestWebsocketJSON(t *testing.T) {
+	startFakeBookingApp()
+	closed := make(chan error, 1)
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		c := NewController(NewRequest(ws.Request()), NewResponse(httptest.NewRecorder()))
+		c.Request.Websocket = ws
+
+		var msg websocketMessage
+		if err := c.ReceiveJSON(&msg); err != nil {
+			t.Errorf("Failed to receive: %s", err)
+			return
+		}
+		msg.Count++
+		if err := c.SendJSON(msg); err != nil {
+			t.Errorf("Failed to send: %s", err)
+		}
+		closed <- c.ReceiveJSON(&msg)
+	}))
+	defer server.Close()
+
+	ws, err := websocket.Dial(strings.Replace(server.URL, "http", "ws", 1), "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	if err = websocket.JSON.Send(ws, websocketMessage{"hello", 1}); err != nil {
+		t.Fatalf("Failed to send: %s", err)
+	}
+	var reply websocketMessage
+	if err = websocket.JSON.Receive(ws, &reply); err != nil {
+		t.Fatalf("Failed to receive: %s", err)
+	}
+	eq(t, "reply", websocketMessage{"hello", 2}, reply)
+
+	_ = ws.Close()
+	eq(t, "error on close", io.EOF, <-closed)
+}
+
+func TestWebsocketHelpersWithoutWebsocket(t *testing.T) {
+	c := NewController(NewRequest(showRequest), NewResponse(httptest.NewRecorder()))
+	eq(t, "send", ErrNoWebsocket, c.SendText("hello"))
+	_, err := c.ReceiveText()
+	eq(t, "receive", ErrNoWebsocket, err)
+}
+
+func TestWebsocketIdleTimeout(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("websocket.idle.timeout", "1")
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+
+	ws, err := websocket.Dial(strings.Replace(server.URL, "http", "ws", 1)+"/hotels/stream", "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer waitWebsocketActions(t)
+	defer ws.Close()
+
+	// Traffic keeps the socket open
+	var reply string
+	for i := 0; i < 3; i++ {
+		time.Sleep(500 * time.Millisecond)
+		if err = websocket.Message.Send(ws, "ping"); err != nil {
+			t.Fatalf("Failed to send: %s", err)
+		}
+		if err = websocket.Message.Receive(ws, &reply); err != nil {
+			t.Fatalf("Failed to receive: %s", err)
+		}
+	}
+
+	// Idling closes it, releasing the wait group
+	released := make(chan struct{})
+	go func() {
+		websocketConns.Wait()
+		close(released)
+	}()
+	select {
+	case <-released:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Idle websocket was not closed")
+	}
+	if err = websocket.Message.Receive(ws, &reply); err == nil {
+		t.Error("Expected the idle websocket to be closed")
+	}
+}
+
+func TestWebsocketOrigin(t *testing.T) {
+	startFakeBookingApp()
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+
+	handshake := func(origin string) int {
+		req, _ := http.NewRequest("GET", server.URL+"/hotels/stream", nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to request: %s", err)
+		}
+		// The body of an upgraded response is the connection
+		_ = resp.Body.Close()
+		waitWebsocketActions(t)
+		return resp.StatusCode
+	}
+
+	// Same origin by default
+	eq(t, "same origin", http.StatusSwitchingProtocols, handshake(server.URL))
+	eq(t, "other origin", http.StatusForbidden, handshake("http://evil.example.com"))
+	eq(t, "missing origin", http.StatusSwitchingProtocols, handshake(""))
+
+	Config.SetOverride("websocket.origins", "https://app.example.com, http://www.example.com")
+	defer Config.RemoveOverride("websocket.origins")
+	eq(t, "allowed origin", http.StatusSwitchingProtocols, handshake("https://app.example.com"))
+	eq(t, "disallowed origin", http.StatusForbidden, handshake("http://evil.example.com"))
+	eq(t, "unlisted own origin", http.StatusForbidden, handshake(server.URL))
+	eq(t, "missing origin", http.StatusSwitchingProtocols, handshake(""))
+}
+
+func TestWebsocketDisabled(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOverride("websocket.enabled", "false")
+	defer Config.RemoveOverride("websocket.enabled")
+	server := httptest.NewServer(http.HandlerFunc(handle))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/hotels/stream", nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to request: %s", err)
+	}
+	_ = resp.Body.Close()
+	eq(t, "disabled upgrade", resp.StatusCode, http.StatusBadRequest)
+}
+
+// Test that Stop closes the websockets still open after
+// websocket.shutdown.timeout, rather than waiting for them.
+func TestStopClosesWebsockets(t *testing.T) {
+	startFakeBookingApp()
+	Config.SetOption("websocket.shutdown.timeout", "1")
+	defer Config.SetOption("websocket.shutdown.timeout", "5")
+	addr, stop, err := RunTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := websocket.Dial("ws://"+addr+"/hotels/stream", "", "http://"+addr)
+	if err != nil {
+		stop()
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	defer ws.Close()
+	var reply string
+	if err = websocket.Message.Send(ws, "ping"); err == nil {
+		err = websocket.Message.Receive(ws, &reply)
+	}
+	if err != nil {
+		stop()
+		t.Fatalf("The websocket failed: %s", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop waited for the open websocket")
+	}
+	if err = websocket.Message.Receive(ws, &reply); err == nil {
+		t.Error("Expected the websocket to be closed")
+	}
+}